@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"duplicate-query/pkg/duplicate"
+)
+
+// loadBaseline reads the duplicate groups of an earlier -format json or
+// jsonl run, and returns their normalized queries keyed by hash.
+func loadBaseline(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading baseline: %v", err)
+	}
+
+	// Version 1 of the json output is a bare array of groups, later ones an
+	// object listing them under duplicates, and jsonl has a group per line
+	var groups []jsonGroup
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &groups)
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		for decoder.More() {
+			var value struct {
+				jsonGroup
+				Duplicates []jsonGroup `json:"duplicates"`
+			}
+			if err = decoder.Decode(&value); err != nil {
+				break
+			}
+			if value.Duplicates != nil {
+				groups = append(groups, value.Duplicates...)
+			} else {
+				groups = append(groups, value.jsonGroup)
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing baseline %s: %v", path, err)
+	}
+
+	baseline := make(map[string]string, len(groups))
+	for _, group := range groups {
+		hash := group.Hash
+		if hash == "" {
+			hash = duplicate.Hash(group.Normalized)
+		}
+		baseline[hash] = group.Normalized
+	}
+	return baseline, nil
+}
+
+// applyBaseline removes the groups already in the baseline from duplicates,
+// leaving the new ones, and returns the normalized queries of the baseline
+// groups that are no longer duplicated, sorted.
+func applyBaseline(duplicates map[string][]duplicate.QueryResult, baseline map[string]string) []string {
+	seen := make(map[string]bool)
+	for key := range duplicates {
+		hash := duplicate.Hash(key)
+		if _, ok := baseline[hash]; ok {
+			seen[hash] = true
+			delete(duplicates, key)
+		}
+	}
+
+	var removed []string
+	for hash, normalized := range baseline {
+		if !seen[hash] {
+			removed = append(removed, normalized)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"duplicate-query/pkg/duplicate"
+)
+
+// fileSummary counts how many of a file's queries are duplicated elsewhere.
+type fileSummary struct {
+	FilePath         string `json:"file_path"`
+	DuplicateQueries int    `json:"duplicate_queries"`
+	TotalQueries     int    `json:"total_queries"`
+}
+
+// queriesPerFile counts every query found in each file.
+func queriesPerFile(groups map[string][]duplicate.QueryResult) map[string]int {
+	counts := make(map[string]int)
+	for _, results := range groups {
+		for _, result := range results {
+			counts[result.FilePath]++
+		}
+	}
+	return counts
+}
+
+// summarizeFiles returns the files with duplicate queries, sorted by number
+// of duplicate queries (descending) and by path for equal counts.
+func summarizeFiles(duplicates map[string][]duplicate.QueryResult, fileQueries map[string]int) []fileSummary {
+	counts := make(map[string]int)
+	for _, results := range duplicates {
+		for _, result := range results {
+			counts[result.FilePath]++
+		}
+	}
+
+	summaries := make([]fileSummary, 0, len(counts))
+	for path, count := range counts {
+		summaries = append(summaries, fileSummary{
+			FilePath:         path,
+			DuplicateQueries: count,
+			TotalQueries:     fileQueries[path],
+		})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].DuplicateQueries != summaries[j].DuplicateQueries {
+			return summaries[i].DuplicateQueries > summaries[j].DuplicateQueries
+		}
+		return summaries[i].FilePath < summaries[j].FilePath
+	})
+	return summaries
+}
+
+func printByFile(w io.Writer, duplicates map[string][]duplicate.QueryResult, fileQueries map[string]int, opts Options) error {
+	summaries := summarizeFiles(duplicates, fileQueries)
+
+	switch opts.Format {
+	case "text":
+		if len(summaries) == 0 {
+			if !opts.Quiet {
+				fmt.Fprintln(w, "No duplicate queries found")
+			}
+			return nil
+		}
+
+		if !opts.Quiet {
+			fmt.Fprintf(w, "Found %d files with duplicate queries\n", len(summaries))
+		}
+		for _, summary := range summaries {
+			fmt.Fprintf(w, "Duplicates: %d -- Queries: %d -- %s\n", summary.DuplicateQueries, summary.TotalQueries, summary.FilePath)
+		}
+		return nil
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summaries)
+	default:
+		return fmt.Errorf("output format %q is not supported with -by-file", opts.Format)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"duplicate-query/pkg/duplicate"
+)
+
+// explain writes the normalization steps of the -explain query, read from
+// stdin when it is -.
+func explain(w io.Writer, stdin io.Reader, opts Options) error {
+	query := opts.Explain
+	if query == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("error reading query: %v", err)
+		}
+		query = string(data)
+	}
+
+	fmt.Fprintf(w, "Query:\t %s\n", indentLines(query))
+	steps := duplicate.ExplainQuery(query, opts.NormalizeOptions)
+	for i, step := range steps {
+		fmt.Fprintf(w, "%2d. %s:\n\t %s\n", i+1, step.Name, indentLines(step.Result))
+	}
+	fmt.Fprintf(w, "Normalized:\t %s\n", duplicate.NormalizeQuery(query, opts.NormalizeOptions))
+	return nil
+}
+
+// indentLines indents the lines after the first of a multi-line query to
+// line up with it.
+func indentLines(query string) string {
+	return strings.ReplaceAll(strings.TrimSpace(query), "\n", "\n\t ")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"html/template"
+	"io"
+
+	"duplicate-query/pkg/duplicate"
+)
+
+// htmlTemplate renders a self-contained report: a table of the duplicate
+// groups that can be sorted by clicking its headers, each row expanding to
+// the original query and its occurrences.
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Duplicate queries</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.4em; text-align: left; vertical-align: top; }
+th { cursor: pointer; background: #f4f4f4; }
+code, pre { font-family: monospace; white-space: pre-wrap; }
+summary { cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>Duplicate queries</h1>
+{{if .}}<p>Found {{len .}} duplicate queries</p>
+<table id="report">
+<thead><tr><th data-type="number">Count</th><th>Normalized query</th></tr></thead>
+<tbody>
+{{range .}}<tr>
+<td>{{.Count}}</td>
+<td><details><summary><code>{{.Normalized}}</code></summary>
+<pre>{{.Original}}</pre>
+<ul>{{range .Occurrences}}<li>{{.FilePath}}:{{.Line}}</li>{{end}}</ul>
+</details></td>
+</tr>
+{{end}}</tbody>
+</table>
+<script>
+document.querySelectorAll("#report th").forEach(function (th, column) {
+	var ascending = false;
+	th.addEventListener("click", function () {
+		var tbody = document.querySelector("#report tbody");
+		var rows = Array.from(tbody.rows);
+		var numeric = th.dataset.type === "number";
+		ascending = !ascending;
+		rows.sort(function (a, b) {
+			var x = a.cells[column].textContent, y = b.cells[column].textContent;
+			var order = numeric ? x - y : x.localeCompare(y);
+			return ascending ? order : -order;
+		});
+		rows.forEach(function (row) { tbody.appendChild(row); });
+	});
+});
+</script>
+{{else}}<p>No duplicate queries found</p>
+{{end}}</body>
+</html>
+`))
+
+// printHTML writes the duplicates as a self-contained HTML page.
+func printHTML(w io.Writer, duplicates map[string][]duplicate.QueryResult, opts Options) error {
+	groups := make([]jsonGroup, 0, len(duplicates))
+	for _, k := range sortedKeys(duplicates, opts.SortBy) {
+		groups = append(groups, newJSONGroup(k, duplicates[k], opts))
+	}
+	return htmlTemplate.Execute(w, groups)
+}
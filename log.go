@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// logLevel orders diagnostics by importance; a logger prints the messages at
+// its level and above.
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+// logLevels maps the -log-level values to levels.
+var logLevels = map[string]logLevel{
+	"debug": levelDebug,
+	"info":  levelInfo,
+	"warn":  levelWarn,
+	"error": levelError,
+}
+
+// logger writes diagnostics, as opposed to results, meant for stderr.
+// Warnings get a "Warning: " prefix and debug messages a "Debug: " one;
+// error messages say so themselves.
+type logger struct {
+	w     io.Writer
+	level logLevel
+}
+
+func (l *logger) logf(level logLevel, prefix, format string, args ...any) {
+	if level >= l.level {
+		fmt.Fprintf(l.w, prefix+format+"\n", args...)
+	}
+}
+
+func (l *logger) Debugf(format string, args ...any) { l.logf(levelDebug, "Debug: ", format, args...) }
+func (l *logger) Infof(format string, args ...any)  { l.logf(levelInfo, "", format, args...) }
+func (l *logger) Warnf(format string, args ...any)  { l.logf(levelWarn, "Warning: ", format, args...) }
+func (l *logger) Errorf(format string, args ...any) { l.logf(levelError, "", format, args...) }
@@ -1,241 +1,718 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
-	"runtime"
-	"sort"
+	"runtime/debug"
+	"strconv"
 	"strings"
-	"sync"
+	"time"
+
+	"duplicate-query/pkg/duplicate"
 )
 
-type QueryResult struct {
-	FilePath   string
-	Query      string
-	Normalized string
+// exitError is returned when the scan itself fails, so it can be told apart
+// from the configurable --exit-code used for duplicates.
+const exitError = 2
+
+// exitInterrupted is returned when the scan was stopped with Ctrl-C, as is
+// customary for SIGINT.
+const exitInterrupted = 130
+
+// Options holds the command line configuration: the scan settings passed to
+// the duplicate package plus settings that only affect the CLI output. The
+// JSON keys match the flag names so a --config file reads like the command
+// line.
+type Options struct {
+	duplicate.Config
+	Format           string `json:"format"`
+	FailOnDuplicates bool   `json:"fail-on-duplicates"`
+	ExitCode         int    `json:"exit-code"`
+	ShowProgress     bool   `json:"progress"`
+	ByFile           bool   `json:"by-file"`
+	ShowOriginal     bool   `json:"show-original"`
+	Stdin            bool   `json:"stdin"`
+	Allowlist        string `json:"allowlist"`
+	Verbose          bool   `json:"verbose"`
+	ListFiles        bool   `json:"list-files"`
+	HashOutput       bool   `json:"hash-output"`
+	ShowParams       bool   `json:"show-params"`
+	SummaryOnly      bool   `json:"summary-only"`
+	RulesFile        string `json:"rules"`
+	Stats            bool   `json:"stats"`
+	GroupByType      bool   `json:"group-by-type"`
+	Output           string `json:"output"`
+	// MaxDepth is the -max-depth flag, where 0 means only the files
+	// directly in the folder and -1 no limit. Config.MaxDepth counts one
+	// level more so that its zero value means no limit.
+	MaxDepth       int    `json:"max-depth"`
+	SortBy         string `json:"sort-by"`
+	CachePath      string `json:"cache"`
+	Quiet          bool   `json:"quiet"`
+	Representative string `json:"representative"`
+	NPlusOne       int    `json:"n-plus-one-threshold"`
+	RelativePaths  bool   `json:"relative-paths"`
+	LogLevel       string `json:"log-level"`
+	Baseline       string `json:"baseline"`
+	StatusFile     string `json:"status-file"`
+	Top            int    `json:"top"`
+	CrossModule    bool   `json:"cross-module"`
+	Explain        string `json:"-"`
+	Tree           bool   `json:"tree"`
+	MaxDuplicates  int    `json:"max-duplicates"`
+	// Sample is the percentage of the files found that are scanned, all
+	// of them at 100. Seed picks them, a random seed if 0.
+	Sample float64 `json:"sample"`
+	Seed   int64   `json:"seed"`
+}
+
+// identifierRe matches a lowercase SQL identifier, as found in normalized
+// queries.
+var identifierRe = regexp.MustCompile(`^[a-z_]\w*$`)
+
+// listFlag is a flag.Value holding a comma separated list.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
 }
 
-type Config struct {
-	FolderPath    string
-	IgnoreFolders []string
-	FileType      string
-	NumWorkers    int
+func (l *listFlag) Set(value string) error {
+	*l = splitList(value)
+	return nil
 }
 
-func parseFlags() Config {
-	folderPath := flag.String("folder", ".", "Folder path to scan")
-	ignoreFolders := flag.String("ignore", "vendor,node_modules", "Comma separated list of folders to ignore")
-	fileType := flag.String("type", ".php", "File type to scan")
-	numWorkers := flag.Int("workers", runtime.NumCPU(), "Number of worker goroutines")
-	flag.Parse()
+// sizeFlag is a flag.Value holding a number of bytes, optionally followed by
+// a KB, MB or GB suffix (powers of 1024).
+type sizeFlag int64
 
-	return Config{
-		FolderPath:    *folderPath,
-		IgnoreFolders: strings.Split(*ignoreFolders, ","),
-		FileType:      *fileType,
-		NumWorkers:    *numWorkers,
+func (s *sizeFlag) String() string {
+	return strconv.FormatInt(int64(*s), 10)
+}
+
+func (s *sizeFlag) Set(value string) error {
+	size, err := parseSize(value)
+	if err != nil {
+		return err
 	}
+	*s = sizeFlag(size)
+	return nil
 }
 
-func findFiles(config Config) ([]string, error) {
-	var files []string
-	err := filepath.Walk(config.FolderPath, func(path string, info os.FileInfo, err error) error {
+// workersFlag is a flag.Value holding a number of workers, or auto, stored
+// as zero, to let ProcessFiles pick it.
+type workersFlag int
+
+func (n *workersFlag) String() string {
+	if *n == 0 {
+		return "auto"
+	}
+	return strconv.Itoa(int(*n))
+}
+
+func (n *workersFlag) Set(value string) error {
+	if value == "auto" {
+		*n = 0
+		return nil
+	}
+	workers, err := strconv.Atoi(value)
+	if err != nil || workers < 1 {
+		return fmt.Errorf("must be auto or at least 1")
+	}
+	*n = workersFlag(workers)
+	return nil
+}
+
+func parseFlags() (Options, error) {
+	opts := Options{
+		Config: duplicate.Config{
+			IgnoreFolders: []string{"vendor", "node_modules"},
+			FileTypes:     []string{".php"},
+		},
+	}
+
+	flag.StringVar(&opts.FolderPath, "folder", ".", "Folder path to scan, a single file to scan, or - to read file paths from stdin. A comma separated list scans several folders together")
+	flag.BoolVar(&opts.Stdin, "stdin", false, "Read the files to scan from stdin, one path per line, instead of walking -folder")
+	flag.Var((*listFlag)(&opts.IgnoreFolders), "ignore", "Comma separated `list` of folders or glob patterns to ignore")
+	flag.Var((*listFlag)(&opts.FileTypes), "type", "Comma separated `list` of file types to scan")
+	flag.Var((*listFlag)(&opts.Include), "include", "Comma separated `list` of glob patterns, such as **/migrations/*.sql; only files matching one of them are scanned. -ignore takes precedence")
+	flag.Var((*workersFlag)(&opts.NumWorkers), "workers", "Number of worker goroutines, or auto (the default) to read files with several goroutines per CPU while analyzing at most one file per CPU")
+	flag.StringVar(&opts.Output, "output", "", "Write the results to this file instead of stdout, creating its directory if needed")
+	flag.StringVar(&opts.Format, "format", "text", "Output format (text|json|jsonl|csv|sarif|html)")
+	flag.StringVar(&opts.Extractor, "extractor", duplicate.ExtractorTokenizer, "How SQL is found in non-Go files (tokenizer|regex). regex is the older, less accurate extractor")
+	flag.IntVar(&opts.MinCount, "min-count", 2, "Minimum number of occurrences for a query to be reported")
+	flag.IntVar(&opts.MaxDepth, "max-depth", -1, "Only descend this many directory levels below -folder. 0 only scans the files directly in it, -1 means no limit")
+	flag.BoolVar(&opts.FollowSymlinks, "follow-symlinks", false, "Walk symlinked directories, visiting each directory once")
+	flag.BoolVar(&opts.CrossModule, "cross-module", false, "Only report queries duplicated across different top-level directories of -folder, and list those directories")
+	flag.BoolVar(&opts.DistinctFiles, "distinct-files", false, "Only report queries found in at least -min-count different files, and show the number of files")
+	flag.BoolVar(&opts.UseGitignore, "use-gitignore", false, "Skip files and folders matched by .gitignore files")
+	flag.Float64Var(&opts.Similarity, "similarity", 0, "Group near-duplicate queries whose token similarity is at least this value (0-1). Compares every pair of queries, so it is slow on large codebases")
+	flag.BoolVar(&opts.FailOnDuplicates, "fail-on-duplicates", false, "Exit with -exit-code when duplicates are found, or more than -max-duplicates of them")
+	flag.IntVar(&opts.ExitCode, "exit-code", 1, "Exit code used by -fail-on-duplicates")
+	flag.IntVar(&opts.MaxDuplicates, "max-duplicates", 0, "Budget of duplicate queries: -fail-on-duplicates and -baseline only fail when there are more than this many")
+	flag.StringVar(&opts.Delimiter, "delimiter", ";", "Statement delimiter, such as $$ or GO (on a line of its own), for files without DELIMITER lines. Not used by -extractor=regex")
+	flag.StringVar(&opts.Dialect, "dialect", duplicate.DialectMySQL, "SQL dialect (mysql|postgres|ansi). Double quotes are strings in mysql and identifiers otherwise")
+	flag.StringVar(&opts.NumToken, "num-token", "N", "Token number literals are replaced with in normalized queries")
+	flag.StringVar(&opts.StrToken, "str-token", "S", "Token string literals are replaced with in normalized queries")
+	flag.BoolVar(&opts.KeepStringLiterals, "keep-string-literals", false, "Keep the contents of string literals instead of collapsing them to S")
+	flag.BoolVar(&opts.NormalizeAliases, "normalize-aliases", false, "Rewrite simple table and column aliases to positional placeholders")
+	flag.BoolVar(&opts.MergePlaceholders, "merge-placeholders", false, "Treat literals and prepared statement placeholders (?, :id, $1, @p1) alike, so inlined and bound forms of a query are duplicates")
+	flag.BoolVar(&opts.NormalizeBooleans, "normalize-booleans", false, "Treat = TRUE and = FALSE like comparisons with the numbers 1 and 0")
+	flag.BoolVar(&opts.NormalizeTableSuffixes, "normalize-table-suffixes", false, "Treat tables after FROM, JOIN, INTO and UPDATE that only differ in a numeric suffix, such as orders_2023 and orders_2024, as the same table")
+	flag.StringVar(&opts.RulesFile, "rules", "", "JSON file of custom normalization rules, run after or instead of the built-in ones")
+	flag.BoolVar(&opts.SortColumns, "sort-columns", false, "Treat queries that list the same columns in a different order as duplicates. Heuristic: also reorders function arguments")
+	flag.BoolVar(&opts.NormalizeOrderBy, "normalize-orderby", false, "Treat ORDER BY keys with an explicit ASC like keys without a direction")
+	flag.BoolVar(&opts.SortOrderBy, "sort-orderby", false, "Treat queries whose ORDER BY lists the same keys in a different order as duplicates, implies -normalize-orderby. Heuristic: the order of the results differs")
+	flag.IntVar(&opts.ContextLines, "context", 0, "Number of source lines to show before and after each query (max 20)")
+	flag.BoolVar(&opts.HashOutput, "hash-output", false, "Include a stable 12 character hash of each normalized query in text and CSV output (JSON always includes it)")
+	flag.Float64Var(&opts.Sample, "sample", 100, "Only scan this percentage (0-100) of the files found, picked at random, for a quick estimate on large codebases")
+	flag.Int64Var(&opts.Seed, "seed", 0, "Seed picking the files of -sample, so runs with the same seed scan the same files. 0 picks a random seed and logs it")
+	flag.IntVar(&opts.MaxOpenFiles, "max-open-files", 0, "Maximum number of files read at once, whatever -workers is. 0 uses half the open file limit (ulimit -n) when the OS reports one")
+	flag.Var((*sizeFlag)(&opts.MaxFileSize), "max-file-size", "Skip files larger than this `size`, in bytes or with a KB, MB or GB suffix. 0 means no limit")
+	flag.StringVar(&opts.CachePath, "cache", "", "JSON `file` remembering the queries of each file, so unchanged files are not analyzed again")
+	flag.StringVar(&opts.SortBy, "sort-by", sortByCount, "Order of the duplicate queries (count|complexity). complexity weighs length, joins and subqueries by the number of occurrences")
+	flag.BoolVar(&opts.Quiet, "quiet", false, "Don't print the \"Found ...\" and \"No duplicate queries found\" lines of text output; errors and warnings still go to stderr")
+	flag.StringVar(&opts.Representative, "representative", representativeFirst, "Which original query to show for each group (first|shortest|longest), in text output with -show-original and in JSON")
+	flag.IntVar(&opts.NPlusOne, "n-plus-one-threshold", 0, "Also report queries found inside loops, possible N+1 queries, when at least this many of their occurrences are. 0 disables the report")
+	flag.BoolVar(&opts.RelativePaths, "relative-paths", false, "Report file paths relative to -folder, the folder containing all of them when it lists several, or the current directory when reading stdin or scanning a single file")
+	flag.IntVar(&opts.Top, "top", 0, "Only report the first N duplicate queries in -sort-by order; text output notes how many were omitted. 0 reports all")
+	flag.BoolVar(&opts.ShowOriginal, "show-original", false, "Show the original query of each duplicate group in text output")
+	flag.BoolVar(&opts.GroupByType, "group-by-type", false, "Group the text output by statement type (SELECT, INSERT, ...)")
+	flag.BoolVar(&opts.ShowParams, "show-params", false, "Show the distinct literal values each duplicate query is used with, in text and JSON output")
+	flag.BoolVar(&opts.Tree, "tree", false, "Print the duplicate queries under the directories and files they occur in, as an indented tree")
+	flag.BoolVar(&opts.ByFile, "by-file", false, "Report which files contribute the most duplicate queries instead of the duplicate groups")
+	flag.StringVar(&opts.Explain, "explain", "", "Print each normalization step that changes this `query`, or the query read from stdin if -, and exit without scanning")
+	flag.BoolVar(&opts.ListFiles, "list-files", false, "Print the files that would be scanned, one per line, and exit without analyzing them")
+	flag.BoolVar(&opts.SummaryOnly, "summary-only", false, "Only print the number of duplicate queries and of their occurrences")
+	flag.BoolVar(&opts.Verbose, "verbose", false, "List the files that could not be read")
+	flag.StringVar(&opts.StatusFile, "status-file", "", "Also write the number of duplicate groups, occurrences and files scanned and the exit code as compact JSON to this `file`")
+	flag.BoolVar(&opts.Stats, "stats", false, "Print file and query counts and the time spent finding and analyzing files to stderr")
+	flag.StringVar(&opts.LogLevel, "log-level", "info", "Diagnostics printed to stderr (debug|info|warn|error); results always go to stdout or -output")
+	flag.BoolVar(&opts.ShowProgress, "progress", false, "Print scan progress to stderr")
+	flag.StringVar(&opts.Baseline, "baseline", "", "JSON or JSONL `file` from an earlier run; only report duplicate queries that aren't in it, and exit with -exit-code if there are any")
+	flag.StringVar(&opts.Allowlist, "allowlist", "", "File of normalized queries or their hashes, one per line, that are duplicated on purpose and should not be reported")
+	configPath := flag.String("config", "", "JSON file with option values, keyed by flag name. Flags given on the command line take precedence")
+	flag.Parse()
+
+	if *configPath != "" {
+		if err := loadConfigFile(*configPath, &opts); err != nil {
+			return opts, err
+		}
+
+		// Parse again so flags given on the command line override the file
+		flag.Parse()
+	}
+
+	if opts.Extractor != duplicate.ExtractorTokenizer && opts.Extractor != duplicate.ExtractorRegex {
+		return opts, fmt.Errorf("invalid -extractor value %q: must be tokenizer or regex", opts.Extractor)
+	}
+
+	if opts.MaxDepth >= 0 {
+		opts.Config.MaxDepth = opts.MaxDepth + 1
+	}
+
+	if opts.RulesFile != "" {
+		rules, replace, err := duplicate.LoadRules(opts.RulesFile)
 		if err != nil {
-			return err
+			return opts, err
 		}
+		opts.Rules, opts.ReplaceBuiltinRules = rules, replace
+	}
 
-		if info.IsDir() {
-			for _, folder := range config.IgnoreFolders {
-				if info.Name() == folder {
-					return filepath.SkipDir
-				}
-			}
+	if opts.SortBy != sortByCount && opts.SortBy != sortByComplexity {
+		return opts, fmt.Errorf("invalid -sort-by value %q: must be count or complexity", opts.SortBy)
+	}
+
+	switch opts.Representative {
+	case representativeFirst, representativeShortest, representativeLongest:
+	default:
+		return opts, fmt.Errorf("invalid -representative value %q: must be first, shortest or longest", opts.Representative)
+	}
+
+	if _, ok := logLevels[opts.LogLevel]; !ok {
+		return opts, fmt.Errorf("invalid -log-level value %q: must be debug, info, warn or error", opts.LogLevel)
+	}
+
+	if opts.Delimiter == "" || strings.ContainsAny(opts.Delimiter, " \t\r\n") {
+		return opts, fmt.Errorf("invalid -delimiter value %q: must be non-empty without spaces", opts.Delimiter)
+	}
+
+	if opts.NPlusOne > 0 && opts.Format != "text" {
+		return opts, fmt.Errorf("output format %q is not supported with -n-plus-one-threshold", opts.Format)
+	}
+	opts.DetectLoops = opts.NPlusOne > 0
+
+	switch opts.Dialect {
+	case duplicate.DialectMySQL, duplicate.DialectPostgres, duplicate.DialectANSI:
+	default:
+		return opts, fmt.Errorf("invalid -dialect value %q: must be mysql, postgres or ansi", opts.Dialect)
+	}
+
+	if err := checkToken("-num-token", opts.NumToken); err != nil {
+		return opts, err
+	}
+	if err := checkToken("-str-token", opts.StrToken); err != nil {
+		return opts, err
+	}
+	if opts.NumToken == opts.StrToken {
+		return opts, fmt.Errorf("invalid -num-token and -str-token values %q: must differ so numbers and strings are told apart", opts.NumToken)
+	}
+
+	if opts.MaxDuplicates < 0 {
+		return opts, fmt.Errorf("invalid -max-duplicates value %d: must be 0 or more", opts.MaxDuplicates)
+	}
+
+	if opts.Sample <= 0 || opts.Sample > 100 {
+		return opts, fmt.Errorf("invalid -sample value %g: must be more than 0 and at most 100", opts.Sample)
+	}
+
+	if opts.MaxOpenFiles < 0 {
+		return opts, fmt.Errorf("invalid -max-open-files value %d: must be 0 or more", opts.MaxOpenFiles)
+	}
+
+	if opts.NumWorkers < 0 {
+		return opts, fmt.Errorf("invalid -workers value %d: must be auto, 0 in a config file, or at least 1", opts.NumWorkers)
+	}
+
+	return opts, nil
+}
+
+// loadConfigFile reads the JSON file at path into opts, leaving options the
+// file doesn't mention untouched.
+func loadConfigFile(path string, opts *Options) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(opts); err != nil {
+		return fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// parseSize parses a size such as 512, 64KB or 10MB into a number of bytes.
+func parseSize(value string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"G", 1 << 30},
+		{"M", 1 << 20},
+		{"K", 1 << 10},
+		{"B", 1},
+	}
+
+	number := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(number, unit.suffix) {
+			number = strings.TrimSpace(strings.TrimSuffix(number, unit.suffix))
+			multiplier = unit.size
+			break
 		}
+	}
+
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return size * multiplier, nil
+}
+
+// openOutput creates the file at path, and its parent directories, for the
+// results. An empty path means stdout.
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" {
+		return os.Stdout, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
 
-		if !info.IsDir() && strings.HasSuffix(path, config.FileType) {
-			files = append(files, path)
+// splitList splits a comma separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
+	}
+	return items
+}
 
-		return nil
-	})
-	return files, err
+// checkToken returns an error if token, the value of the -num-token or
+// -str-token flag, is empty or a lowercase identifier, which the lowercased
+// names of a normalized query could be mistaken for.
+func checkToken(flagName, token string) error {
+	if token == "" {
+		return fmt.Errorf("invalid %s value %q: must not be empty", flagName, token)
+	}
+	if identifierRe.MatchString(token) {
+		return fmt.Errorf("invalid %s value %q: must not be a lowercase identifier, which column names could be mistaken for", flagName, token)
+	}
+	return nil
 }
 
-func worker(jobs <-chan string, results chan<- []QueryResult, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for path := range jobs {
-		if res, err := analyzeFile(path); err == nil {
-			results <- res
+// folders returns the folders -folder lists. A path that exists is a single
+// folder even if its name contains a comma.
+func folders(folderPath string) []string {
+	if _, err := os.Stat(folderPath); err == nil || !strings.Contains(folderPath, ",") {
+		return []string{folderPath}
+	}
+	return splitList(folderPath)
+}
+
+// findFiles returns the files of every folder -folder lists, each file once
+// when the folders overlap.
+func findFiles(ctx context.Context, config duplicate.Config) ([]string, error) {
+	roots := folders(config.FolderPath)
+	if len(roots) == 1 {
+		return duplicate.FindFilesContext(ctx, config)
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		config.FolderPath = root
+		found, err := duplicate.FindFilesContext(ctx, config)
+		for _, file := range found {
+			if key := filepath.Clean(file); !seen[key] {
+				seen[key] = true
+				files = append(files, file)
+			}
+		}
+		if err != nil {
+			return files, err
 		}
 	}
+	return files, nil
 }
 
-func processFiles(files []string, config Config) []QueryResult {
-	jobs := make(chan string, len(files))
-	results := make(chan []QueryResult, len(files))
-	var wg sync.WaitGroup
+// pathBase returns the folder -relative-paths reports paths relative to:
+// -folder when it is a folder, the closest folder containing all of them
+// when it lists several, and the current directory otherwise.
+func pathBase(opts Options) string {
+	if !opts.Stdin && opts.FolderPath != "-" {
+		if roots := folders(opts.FolderPath); len(roots) > 1 {
+			return commonDir(roots)
+		}
+		if info, err := os.Stat(opts.FolderPath); err == nil && info.IsDir() {
+			return opts.FolderPath
+		}
+	}
+	return "."
+}
 
-	// Start workers
-	for i := 0; i < config.NumWorkers; i++ {
-		wg.Add(1)
-		go worker(jobs, results, &wg)
+// commonDir returns the closest directory containing every one of paths, as
+// an absolute path unless paths are all below the current directory.
+func commonDir(paths []string) string {
+	var common []string
+	for i, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "."
+		}
+		if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+			abs = filepath.Dir(abs)
+		}
+		parts := strings.Split(abs, string(filepath.Separator))
+		if i == 0 {
+			common = parts
+			continue
+		}
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
 	}
 
-	// Send jobs
-	for _, file := range files {
-		jobs <- file
+	dir := strings.Join(common, string(filepath.Separator))
+	if dir == "" {
+		dir = string(filepath.Separator)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, dir); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
 	}
-	close(jobs)
+	return dir
+}
 
-	// Wait for workers in a separate goroutine
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+// toolVersion returns the version of the module the binary was built from,
+// such as v1.4.0, or "devel" when it is unknown.
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "devel"
+}
 
-	// Collect results
-	var allQueries []QueryResult
-	for result := range results {
-		allQueries = append(allQueries, result...)
+// reportRoot returns the directory -cross-module and -tree organize the
+// reported paths by, or "" when -relative-paths already made them relative
+// to it.
+func reportRoot(opts Options) string {
+	if opts.RelativePaths {
+		return ""
 	}
+	return pathBase(opts)
+}
 
-	return allQueries
+// relativePath returns path relative to base, or path unchanged when base is
+// empty or path can't be made relative to it.
+func relativePath(base, path string) string {
+	if base == "" {
+		return path
+	}
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return path
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(absBase, absPath)
+	if err != nil {
+		return path
+	}
+	return rel
 }
 
-func analyzeFile(path string) ([]QueryResult, error) {
-	data, err := os.ReadFile(path)
+func main() {
+	opts, err := parseFlags()
 	if err != nil {
-		return nil, fmt.Errorf("error reading file: %v", err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
 	}
 
-	matches := findSQLQueries(string(data))
-	results := make([]QueryResult, len(matches))
-	for i, match := range matches {
-		results[i] = QueryResult{
-			FilePath:   path,
-			Query:      match,
-			Normalized: normalizeQuery(match),
+	log := &logger{w: os.Stderr, level: logLevels[opts.LogLevel]}
+
+	if opts.Explain != "" {
+		if err := explain(os.Stdout, os.Stdin, opts); err != nil {
+			log.Errorf("%v", err)
+			os.Exit(exitError)
+		}
+		return
+	}
+	opts.Warnf = log.Warnf
+	if opts.ShowProgress {
+		opts.Progress = func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\rScanned %d/%d files", done, total)
 		}
 	}
-	return results, nil
-}
 
-func findDuplicates(queries []QueryResult) map[string][]QueryResult {
-	duplicates := make(map[string][]QueryResult)
-	for _, query := range queries {
-		duplicates[query.Normalized] = append(duplicates[query.Normalized], query)
+	// Stop the scan on Ctrl-C but still report what was found so far. A
+	// second Ctrl-C kills the program as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	start := time.Now()
+	var files []string
+	if opts.Stdin || opts.FolderPath == "-" {
+		files, err = duplicate.ReadFileList(os.Stdin, opts.Config)
+		if err != nil {
+			log.Errorf("Error reading file list: %v", err)
+			os.Exit(exitError)
+		}
+	} else {
+		files, err = findFiles(ctx, opts.Config)
+		if err != nil && ctx.Err() == nil {
+			log.Errorf("Error walking folder: %v", err)
+			os.Exit(exitError)
+		}
 	}
 
-	for key, value := range duplicates {
-		if len(value) == 1 {
-			delete(duplicates, key)
+	if opts.Sample < 100 {
+		if opts.Seed == 0 {
+			opts.Seed = time.Now().UnixNano()
 		}
+		found := len(files)
+		files = duplicate.SampleFiles(files, opts.Sample, opts.Seed)
+		log.Infof("Sampling %d of %d files (%g%%) with -seed=%d, results are an estimate", len(files), found, opts.Sample, opts.Seed)
 	}
-	return duplicates
-}
 
-func printResults(duplicates map[string][]QueryResult) {
-	if len(duplicates) == 0 {
-		fmt.Println("No duplicate queries found")
+	var base string
+	if opts.RelativePaths {
+		base = pathBase(opts)
+	}
+
+	if opts.ListFiles {
+		for _, file := range files {
+			fmt.Println(relativePath(base, file))
+		}
 		return
 	}
 
-	fmt.Printf("Found %d duplicate queries\n", len(duplicates))
+	discovery := time.Since(start)
+	walkInterrupted := ctx.Err() != nil
+	log.Debugf("found %d files to scan in %v", len(files), discovery.Round(time.Millisecond))
+
+	if opts.CachePath != "" {
+		opts.Cache, err = duplicate.LoadCache(opts.CachePath, opts.Config)
+		if err != nil {
+			log.Errorf("Error loading cache: %v", err)
+			os.Exit(exitError)
+		}
+	}
 
-	// Convert map keys to slice for sorting
-	keys := make([]string, 0, len(duplicates))
-	for k := range duplicates {
-		keys = append(keys, k)
+	start = time.Now()
+	scan := duplicate.ProcessFilesContext(ctx, files, opts.Config)
+	analysis := time.Since(start)
+	if opts.ShowProgress {
+		fmt.Fprintln(os.Stderr)
 	}
+	log.Debugf("analyzed %d files in %v", scan.Analyzed, analysis.Round(time.Millisecond))
 
-	// Sort by number of values (descending) and alphabetically for equal counts
-	sort.Slice(keys, func(i, j int) bool {
-		if len(duplicates[keys[i]]) != len(duplicates[keys[j]]) {
-			return len(duplicates[keys[i]]) > len(duplicates[keys[j]])
+	if opts.Cache != nil {
+		if err := opts.Cache.Save(); err != nil {
+			log.Warnf("%v", err)
 		}
-		return keys[i] < keys[j]
-	})
+	}
 
-	// Print sorted results
-	for _, k := range keys {
-		fmt.Printf("Count: %d -- Normalized Query:\t %s\n", len(duplicates[k]), k)
+	if len(scan.Errors) > 0 {
+		log.Warnf("%d files could not be read, results are incomplete", len(scan.Errors))
+		if opts.Verbose || log.level == levelDebug {
+			for _, err := range scan.Errors {
+				log.Errorf("\t%v", err)
+			}
+		} else {
+			log.Infof("Run with -verbose to list them")
+		}
 	}
-}
 
-func normalizeQuery(query string) string {
-	// First collapse all whitespace variants into single spaces
-	normalized := regexp.MustCompile(`[\s\n\r\t]+`).ReplaceAllString(query, " ")
-	normalized = strings.TrimSpace(normalized)
-	normalized = strings.ToLower(normalized)
+	if scan.Ignored > 0 {
+		log.Infof("Ignored %d queries marked with dqf:ignore", scan.Ignored)
+	}
 
-	replacements := []struct {
-		pattern     string
-		replacement string
-	}{
-		{`\s*=\s*`, " = "},  // Normalize spaces around equals
-		{`\s*,\s*`, ", "},   // Normalize spaces around commas
-		{`\s+`, " "},        // Any remaining multiple spaces to single
-		{`\d+`, "N"},        // Numbers to N
-		{`'[^']*'`, "S"},    // Quoted strings to S
-		{`"[^"]*"`, "S"},    // Double quoted strings to S
-		{`\s*\(\s*`, " ( "}, // Normalize spaces around parentheses
-		{`\s*\)\s*`, " ) "},
+	if opts.RelativePaths {
+		for _, results := range scan.Groups {
+			for i := range results {
+				results[i].FilePath = relativePath(base, results[i].FilePath)
+			}
+		}
 	}
 
-	for _, r := range replacements {
-		re := regexp.MustCompile(r.pattern)
-		normalized = re.ReplaceAllString(normalized, r.replacement)
+	// Count every query per file before the groups are reduced to duplicates
+	fileQueries := queriesPerFile(scan.Groups)
+	uniqueQueries := len(scan.Groups)
+	var loops map[string][]duplicate.QueryResult
+	if opts.NPlusOne > 0 {
+		loops = duplicate.LoopQueries(scan.Groups, opts.NPlusOne)
 	}
+	duplicates := duplicate.Duplicates(scan.Groups, opts.Config)
 
-	return normalized
-}
+	if opts.Allowlist != "" {
+		allowlist, err := duplicate.LoadAllowlist(opts.Allowlist)
+		if err != nil {
+			log.Errorf("%v", err)
+			os.Exit(exitError)
+		}
+		if suppressed := duplicate.Suppress(duplicates, allowlist); suppressed > 0 {
+			log.Infof("Suppressed %d allowlisted duplicate queries", suppressed)
+		}
+	}
+
+	if opts.CrossModule {
+		removed := duplicate.KeepCrossModule(duplicates, reportRoot(opts))
+		log.Debugf("dropped %d duplicate queries found in a single module", removed)
+	}
 
-func findSQLQueries(text string) []string {
-	// More comprehensive SQL pattern
-	pattern := `(?i)(?:SELECT\s+[\s\S]+?(?:FROM[\s\S]+?)?|` +
-		`INSERT\s+INTO[\s\S]+?|` +
-		`UPDATE\s+\w+\s+SET[\s\S]+?|` +
-		`DELETE\s+FROM[\s\S]+?|` +
-		`CREATE\s+(?:TABLE|DATABASE|INDEX)[\s\S]+?|` +
-		`ALTER\s+TABLE[\s\S]+?|` +
-		`DROP\s+(?:TABLE|DATABASE)[\s\S]+?|` +
-		`TRUNCATE\s+TABLE[\s\S]+?)` +
-		`(?:;|$)` // Match until semicolon or end of string
+	if opts.Baseline != "" {
+		baseline, err := loadBaseline(opts.Baseline)
+		if err != nil {
+			log.Errorf("%v", err)
+			os.Exit(exitError)
+		}
+		removed := applyBaseline(duplicates, baseline)
+		log.Infof("%d duplicate queries are new since the baseline, %d are gone", len(duplicates), len(removed))
+		if opts.Verbose {
+			for _, normalized := range removed {
+				log.Infof("\tgone: %s", normalized)
+			}
+		}
+	}
 
-	re := regexp.MustCompile(pattern)
-	matches := re.FindAllString(text, -1)
+	out, err := openOutput(opts.Output)
+	if err != nil {
+		log.Errorf("Error opening output file: %v", err)
+		os.Exit(exitError)
+	}
 
-	// Clean and validate matches
-	var result []string
-	for _, match := range matches {
-		// Clean up the match
-		cleaned := strings.TrimSpace(match)
+	switch {
+	case opts.SummaryOnly:
+		err = printSummary(out, duplicates, opts)
+	case opts.ByFile:
+		err = printByFile(out, duplicates, fileQueries, opts)
+	case opts.Tree:
+		err = printTree(out, duplicates, opts)
+	default:
+		err = printResults(out, duplicates, opts)
+	}
+	if err == nil && loops != nil {
+		printNPlusOne(out, loops, opts)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Errorf("Error printing results: %v", err)
+		os.Exit(exitError)
+	}
 
-		// Basic validation that it looks like a SQL query
-		if len(cleaned) > 0 &&
-			(strings.HasSuffix(cleaned, ";") ||
-				strings.Contains(strings.ToUpper(cleaned), "SELECT") ||
-				strings.Contains(strings.ToUpper(cleaned), "INSERT") ||
-				strings.Contains(strings.ToUpper(cleaned), "UPDATE")) {
+	if opts.Stats {
+		s := stats{Files: len(files), Unique: uniqueQueries, Discovery: discovery, Analysis: analysis}
+		for _, count := range fileQueries {
+			s.Queries += count
+		}
+		printStats(os.Stderr, s, duplicates)
+	}
 
-			result = append(result, cleaned)
+	failing := opts.FailOnDuplicates || opts.Baseline != ""
+	if failing && opts.MaxDuplicates > 0 {
+		if len(duplicates) > opts.MaxDuplicates {
+			log.Warnf("%d duplicate queries exceed the budget of %d", len(duplicates), opts.MaxDuplicates)
+		} else {
+			log.Infof("%d duplicate queries, within the budget of %d", len(duplicates), opts.MaxDuplicates)
 		}
 	}
-	return result
-}
 
-func main() {
-	config := parseFlags()
-	files, err := findFiles(config)
-	if err != nil {
-		fmt.Printf("Error walking folder: %v\n", err)
-		return
+	exitCode := 0
+	switch {
+	case walkInterrupted || scan.Interrupted:
+		log.Warnf("Scan interrupted after analyzing %d of %d files, results are incomplete", scan.Analyzed, len(files))
+		exitCode = exitInterrupted
+	case failing && len(duplicates) > opts.MaxDuplicates:
+		exitCode = opts.ExitCode
 	}
 
-	queries := processFiles(files, config)
-	duplicates := findDuplicates(queries)
-	printResults(duplicates)
+	if opts.StatusFile != "" {
+		if err := writeStatus(opts.StatusFile, duplicates, scan.Analyzed, exitCode); err != nil {
+			log.Errorf("%v", err)
+			os.Exit(exitError)
+		}
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"duplicate-query/pkg/duplicate"
+)
+
+// printNPlusOne writes the queries found inside loops, which likely run once
+// per iteration, after the regular report.
+func printNPlusOne(w io.Writer, loops map[string][]duplicate.QueryResult, opts Options) {
+	keys := make([]string, 0, len(loops))
+	for k := range loops {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(loops[keys[i]]) != len(loops[keys[j]]) {
+			return len(loops[keys[i]]) > len(loops[keys[j]])
+		}
+		return keys[i] < keys[j]
+	})
+
+	if !opts.Quiet {
+		if len(keys) == 0 {
+			fmt.Fprintln(w, "\nNo possible N+1 queries found")
+		} else {
+			fmt.Fprintf(w, "\nFound %d possible N+1 queries, run inside loops\n", len(keys))
+		}
+	}
+	for _, k := range keys {
+		fmt.Fprintf(w, "Count: %d -- Normalized Query:\t %s\n", len(loops[k]), k)
+		for _, result := range loops[k] {
+			fmt.Fprintf(w, "\t%s:%d\n", result.FilePath, result.Line)
+		}
+	}
+}
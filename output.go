@@ -0,0 +1,357 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"duplicate-query/pkg/duplicate"
+)
+
+// The orders -sort-by accepts.
+const (
+	sortByCount      = "count"
+	sortByComplexity = "complexity"
+)
+
+// The originals -representative can pick for a group.
+const (
+	representativeFirst    = "first"
+	representativeShortest = "shortest"
+	representativeLongest  = "longest"
+)
+
+// jsonSchemaVersion is the schema_version of the json and jsonl output,
+// bumped on changes that can break their consumers. Version 1 was a bare
+// array of groups.
+const jsonSchemaVersion = 2
+
+// jsonReport is the document written by -format json.
+type jsonReport struct {
+	SchemaVersion int         `json:"schema_version"`
+	Sample        *jsonSample `json:"sample,omitempty"`
+	Duplicates    []jsonGroup `json:"duplicates"`
+}
+
+// jsonSample notes that only a -sample of the files was scanned.
+type jsonSample struct {
+	Percent float64 `json:"percent"`
+	Seed    int64   `json:"seed"`
+}
+
+// jsonLine is a line of -format jsonl, a group carrying the schema version
+// so each line can be read on its own.
+type jsonLine struct {
+	SchemaVersion int `json:"schema_version"`
+	jsonGroup
+}
+
+type jsonOccurrence struct {
+	FilePath string `json:"file_path"`
+	Line     int    `json:"line"`
+	Query    string `json:"query"`
+	// Normalized is only set when it differs from the group, which happens
+	// when grouping by similarity.
+	Normalized  string   `json:"normalized_query,omitempty"`
+	ContextLine int      `json:"context_start_line,omitempty"`
+	Context     []string `json:"context,omitempty"`
+}
+
+type jsonGroup struct {
+	Hash        string           `json:"hash"`
+	Normalized  string           `json:"normalized_query"`
+	Count       int              `json:"count"`
+	Files       int              `json:"files,omitempty"`
+	Modules     []string         `json:"modules,omitempty"`
+	Complexity  int              `json:"complexity"`
+	Original    string           `json:"original_query"`
+	Params      []string         `json:"params,omitempty"`
+	Occurrences []jsonOccurrence `json:"occurrences"`
+}
+
+func printResults(w io.Writer, duplicates map[string][]duplicate.QueryResult, opts Options) error {
+	if opts.GroupByType && opts.Format != "text" {
+		return fmt.Errorf("output format %q is not supported with -group-by-type", opts.Format)
+	}
+
+	if opts.Format == "text" {
+		printText(w, duplicates, opts)
+		return nil
+	}
+
+	duplicates = topDuplicates(duplicates, opts)
+	switch opts.Format {
+	case "json":
+		return printJSON(w, duplicates, opts)
+	case "jsonl":
+		return printJSONL(w, duplicates, opts)
+	case "csv":
+		return printCSV(w, duplicates, opts)
+	case "sarif":
+		return printSARIF(w, duplicates, opts)
+	case "html":
+		return printHTML(w, duplicates, opts)
+	default:
+		return fmt.Errorf("unknown output format %q", opts.Format)
+	}
+}
+
+// topDuplicates returns the first opts.Top groups of duplicates in the
+// report order, or duplicates itself when Top is 0 or isn't exceeded.
+func topDuplicates(duplicates map[string][]duplicate.QueryResult, opts Options) map[string][]duplicate.QueryResult {
+	if opts.Top <= 0 || len(duplicates) <= opts.Top {
+		return duplicates
+	}
+	top := make(map[string][]duplicate.QueryResult, opts.Top)
+	for _, k := range sortedKeys(duplicates, opts.SortBy)[:opts.Top] {
+		top[k] = duplicates[k]
+	}
+	return top
+}
+
+// sortedKeys orders the normalized queries by number of occurrences
+// (descending), or by Complexity when sortBy is "complexity", and
+// alphabetically for equal values.
+func sortedKeys(duplicates map[string][]duplicate.QueryResult, sortBy string) []string {
+	keys := make([]string, 0, len(duplicates))
+	scores := make(map[string]int, len(duplicates))
+	for k, results := range duplicates {
+		keys = append(keys, k)
+		if sortBy == sortByComplexity {
+			scores[k] = duplicate.Complexity(k, len(results))
+		} else {
+			scores[k] = len(results)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if scores[keys[i]] != scores[keys[j]] {
+			return scores[keys[i]] > scores[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// sampleNote returns the note appended to the "Found ..." line when only a
+// -sample of the files was scanned.
+func sampleNote(opts Options) string {
+	if opts.Sample >= 100 {
+		return ""
+	}
+	return fmt.Sprintf(" in a %g%% sample of the files (-seed=%d)", opts.Sample, opts.Seed)
+}
+
+func printText(w io.Writer, duplicates map[string][]duplicate.QueryResult, opts Options) {
+	if len(duplicates) == 0 {
+		if !opts.Quiet {
+			fmt.Fprintln(w, "No duplicate queries found")
+		}
+		return
+	}
+
+	if !opts.Quiet {
+		fmt.Fprintf(w, "Found %d duplicate queries%s\n", len(duplicates), sampleNote(opts))
+	}
+
+	// Only print the first -top groups, noting how many were left out
+	total := len(duplicates)
+	duplicates = topDuplicates(duplicates, opts)
+	if omitted := total - len(duplicates); omitted > 0 && !opts.Quiet {
+		defer fmt.Fprintf(w, "... %d more duplicate queries omitted, see -top\n", omitted)
+	}
+
+	if opts.GroupByType {
+		byType := make(map[string][]string)
+		for _, k := range sortedKeys(duplicates, opts.SortBy) {
+			byType[statementType(k)] = append(byType[statementType(k)], k)
+		}
+		types := make([]string, 0, len(byType))
+		for t := range byType {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+
+		for _, t := range types {
+			fmt.Fprintf(w, "\n== %s (%d) ==\n", t, len(byType[t]))
+			for _, k := range byType[t] {
+				printTextGroup(w, k, duplicates[k], opts)
+			}
+		}
+		return
+	}
+
+	// Print sorted results
+	for _, k := range sortedKeys(duplicates, opts.SortBy) {
+		printTextGroup(w, k, duplicates[k], opts)
+	}
+}
+
+// printTextGroup writes the occurrences of the normalized query k.
+func printTextGroup(w io.Writer, k string, results []duplicate.QueryResult, opts Options) {
+	if opts.SortBy == sortByComplexity {
+		fmt.Fprintf(w, "Complexity: %d -- ", duplicate.Complexity(k, len(results)))
+	}
+	if opts.DistinctFiles {
+		fmt.Fprintf(w, "Files: %d -- ", duplicate.CountFiles(results))
+	}
+	if opts.CrossModule {
+		fmt.Fprintf(w, "Modules: %s -- ", strings.Join(duplicate.Modules(results, reportRoot(opts)), ", "))
+	}
+	if opts.HashOutput {
+		fmt.Fprintf(w, "Count: %d -- Hash: %s -- Normalized Query:\t %s\n", len(results), duplicate.Hash(k), k)
+	} else {
+		fmt.Fprintf(w, "Count: %d -- Normalized Query:\t %s\n", len(results), k)
+	}
+	if opts.ShowOriginal {
+		original := strings.ReplaceAll(representative(results, opts.Representative), "\n", "\n\t\t")
+		fmt.Fprintf(w, "\tOriginal:\t %s\n", original)
+	}
+	if opts.ShowParams {
+		printParams(w, duplicate.DistinctParams(results))
+	}
+	for _, result := range results {
+		if result.Normalized != k {
+			fmt.Fprintf(w, "\t%s:%d\t %s\n", result.FilePath, result.Line, result.Normalized)
+		} else {
+			fmt.Fprintf(w, "\t%s:%d\n", result.FilePath, result.Line)
+		}
+
+		for i, line := range result.Context {
+			fmt.Fprintf(w, "\t\t%5d | %s\n", result.ContextLine+i, line)
+		}
+	}
+}
+
+// statementType returns the leading keyword of a normalized query, such as
+// SELECT or INSERT.
+func statementType(normalized string) string {
+	keyword, _, _ := strings.Cut(normalized, " ")
+	return strings.ToUpper(keyword)
+}
+
+// maxParams is how many distinct parameter sets printParams lists.
+const maxParams = 10
+
+// printParams writes the distinct literal values of a group, listing the
+// first maxParams of them.
+func printParams(w io.Writer, params []string) {
+	listed := params[:min(len(params), maxParams)]
+	fmt.Fprintf(w, "\tParams:\t %d distinct: (%s)", len(params), strings.Join(listed, "), ("))
+	if len(params) > len(listed) {
+		fmt.Fprintf(w, " and %d more", len(params)-len(listed))
+	}
+	fmt.Fprintln(w)
+}
+
+func printJSON(w io.Writer, duplicates map[string][]duplicate.QueryResult, opts Options) error {
+	groups := make([]jsonGroup, 0, len(duplicates))
+	for _, k := range sortedKeys(duplicates, opts.SortBy) {
+		groups = append(groups, newJSONGroup(k, duplicates[k], opts))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	report := jsonReport{SchemaVersion: jsonSchemaVersion, Duplicates: groups}
+	if opts.Sample < 100 {
+		report.Sample = &jsonSample{Percent: opts.Sample, Seed: opts.Seed}
+	}
+	return encoder.Encode(report)
+}
+
+// printJSONL writes one compact JSON object per group and line, in the same
+// order as the other formats, so the output can be consumed as a stream.
+func printJSONL(w io.Writer, duplicates map[string][]duplicate.QueryResult, opts Options) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, k := range sortedKeys(duplicates, opts.SortBy) {
+		line := jsonLine{SchemaVersion: jsonSchemaVersion, jsonGroup: newJSONGroup(k, duplicates[k], opts)}
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// representative returns the original query shown for a group: the first
+// occurrence's, or the shortest or longest one, the earliest on ties.
+func representative(results []duplicate.QueryResult, mode string) string {
+	query := results[0].Query
+	for _, result := range results[1:] {
+		switch {
+		case mode == representativeShortest && len(result.Query) < len(query),
+			mode == representativeLongest && len(result.Query) > len(query):
+			query = result.Query
+		}
+	}
+	return query
+}
+
+// newJSONGroup converts the occurrences of the normalized query k for the
+// json and jsonl formats.
+func newJSONGroup(k string, results []duplicate.QueryResult, opts Options) jsonGroup {
+	group := jsonGroup{
+		Hash:        duplicate.Hash(k),
+		Normalized:  k,
+		Count:       len(results),
+		Complexity:  duplicate.Complexity(k, len(results)),
+		Original:    representative(results, opts.Representative),
+		Occurrences: make([]jsonOccurrence, len(results)),
+	}
+	if opts.DistinctFiles {
+		group.Files = duplicate.CountFiles(results)
+	}
+	if opts.CrossModule {
+		group.Modules = duplicate.Modules(results, reportRoot(opts))
+	}
+	if opts.ShowParams {
+		group.Params = duplicate.DistinctParams(results)
+	}
+	for i, result := range results {
+		group.Occurrences[i] = jsonOccurrence{
+			FilePath:    result.FilePath,
+			Line:        result.Line,
+			Query:       result.Query,
+			ContextLine: result.ContextLine,
+			Context:     result.Context,
+		}
+		if result.Normalized != k {
+			group.Occurrences[i].Normalized = result.Normalized
+		}
+	}
+	return group
+}
+
+// printCSV writes one row per occurrence, grouped in the same order as the
+// text output. With -hash-output the rows start with the query hash.
+func printCSV(w io.Writer, duplicates map[string][]duplicate.QueryResult, opts Options) error {
+	writer := csv.NewWriter(w)
+	header := []string{"normalized_query", "count", "file_path", "line", "query"}
+	if opts.HashOutput {
+		header = append([]string{"hash"}, header...)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, k := range sortedKeys(duplicates, opts.SortBy) {
+		count := strconv.Itoa(len(duplicates[k]))
+		for _, result := range duplicates[k] {
+			row := []string{k, count, result.FilePath, strconv.Itoa(result.Line), result.Query}
+			if opts.HashOutput {
+				row = append([]string{duplicate.Hash(k)}, row...)
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
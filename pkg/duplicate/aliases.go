@@ -0,0 +1,89 @@
+package duplicate
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// aliasKeywords can follow a table name without being an alias.
+var aliasKeywords = map[string]bool{
+	"and": true, "as": true, "cross": true, "for": true, "from": true,
+	"full": true, "group": true, "having": true, "inner": true, "into": true,
+	"join": true, "lateral": true, "left": true, "limit": true, "natural": true,
+	"offset": true, "on": true, "or": true, "order": true, "outer": true,
+	"right": true, "select": true, "set": true, "straight_join": true,
+	"union": true, "using": true, "values": true, "where": true, "window": true,
+}
+
+// The regular expressions used by normalizeAliases, compiled once.
+var (
+	tableAsRe = regexp.MustCompile(`\b((?:from|join|update|into) [\w.]+) as `)
+	tableRe   = regexp.MustCompile(`\b(?:from|join|update|into) [\w.]+ ([a-z_]\w*)\b`)
+	columnRe  = regexp.MustCompile(`\bas ([a-z_]\w*)\b`)
+	identRe   = regexp.MustCompile(`[a-z_]\w*`)
+	// castRe matches the end of the text before the parenthesis of a CAST
+	// or CONVERT call, whose "as" is followed by a type, not an alias.
+	castRe = regexp.MustCompile(`\b(?:cast|try_cast|convert) $`)
+)
+
+// normalizeAliases rewrites table and column aliases in a normalized query
+// to positional placeholders (t1, t2, ... and c1, c2, ...) so queries that
+// only differ in the alias names they picked compare equal. Only the simple
+// "table alias", "table as alias" and "expr as alias" forms are recognized.
+func normalizeAliases(query string) string {
+	// "table as alias" and "table alias" mean the same thing
+	query = tableAsRe.ReplaceAllString(query, "$1 ")
+
+	aliases := make(map[string]string)
+	tables, columns := 0, 0
+	for _, m := range tableRe.FindAllStringSubmatch(query, -1) {
+		if alias := m[1]; !aliasKeywords[alias] && aliases[alias] == "" {
+			tables++
+			aliases[alias] = fmt.Sprintf("t%d", tables)
+		}
+	}
+	for _, m := range columnRe.FindAllStringSubmatchIndex(query, -1) {
+		if inCast(query, m[0]) {
+			continue
+		}
+		if alias := query[m[2]:m[3]]; !aliasKeywords[alias] && aliases[alias] == "" {
+			columns++
+			aliases[alias] = fmt.Sprintf("c%d", columns)
+		}
+	}
+	if len(aliases) == 0 {
+		return query
+	}
+
+	// Rewrite every use of an alias, leaving column names qualified by a
+	// table (x.alias) alone
+	locations := identRe.FindAllStringIndex(query, -1)
+	result := []byte(query)
+	for i := len(locations) - 1; i >= 0; i-- {
+		start, end := locations[i][0], locations[i][1]
+		placeholder, ok := aliases[query[start:end]]
+		if !ok || (start > 0 && query[start-1] == '.') {
+			continue
+		}
+		result = append(result[:start], append([]byte(placeholder), result[end:]...)...)
+	}
+	return string(result)
+}
+
+// inCast reports whether pos is directly inside the parentheses of a CAST or
+// CONVERT call.
+func inCast(query string, pos int) bool {
+	depth := 0
+	for i := pos - 1; i >= 0; i-- {
+		switch query[i] {
+		case ')':
+			depth++
+		case '(':
+			if depth == 0 {
+				return castRe.MatchString(query[:i])
+			}
+			depth--
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package duplicate
+
+import "testing"
+
+func TestNormalizeAliases(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "single letter table alias",
+			query: "SELECT a.id FROM users a",
+			want:  "select t1.id from users t1",
+		},
+		{
+			name:  "table as alias",
+			query: "SELECT u.id FROM users AS u",
+			want:  "select t1.id from users t1",
+		},
+		{
+			name:  "joined tables",
+			query: "SELECT o.id, c.name FROM orders o JOIN customers c ON c.id = o.customer_id",
+			want:  "select t1.id, t2.name from orders t1 join customers t2 on t2.id = t1.customer_id",
+		},
+		{
+			name:  "alias used in every clause",
+			query: "SELECT a.id FROM users a WHERE a.status = 'x' ORDER BY a.created_at",
+			want:  "select t1.id from users t1 where t1.status = S order by t1.created_at",
+		},
+		{
+			name:  "column alias",
+			query: "SELECT COUNT(*) AS total FROM orders",
+			want:  "select count ( * ) as c1 from orders",
+		},
+		{
+			name:  "keyword after table is not an alias",
+			query: "SELECT id FROM users WHERE id = 1",
+			want:  "select id from users where id = N",
+		},
+		{
+			name:  "cast type is not an alias",
+			query: "SELECT CAST(price AS int) FROM items",
+			want:  "select cast ( price as int ) from items",
+		},
+		{
+			name:  "cast type next to a column alias",
+			query: "SELECT CAST(COALESCE(price, 0) AS int) AS p FROM items i",
+			want:  "select cast ( coalesce ( price, N ) as int ) as c1 from items t1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeQuery(tt.query, NormalizeOptions{NormalizeAliases: true})
+			if got != tt.want {
+				t.Errorf("NormalizeQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeAliasesGroups(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      string
+		duplicate bool
+	}{
+		{
+			name:      "different table aliases",
+			a:         "SELECT a.id FROM users a",
+			b:         "SELECT u.id FROM users u",
+			duplicate: true,
+		},
+		{
+			name:      "different column aliases",
+			a:         "SELECT COUNT(*) AS total FROM orders",
+			b:         "SELECT COUNT(*) AS n_orders FROM orders",
+			duplicate: true,
+		},
+		{
+			name: "different cast types",
+			a:    "SELECT CAST(price AS int) FROM items",
+			b:    "SELECT CAST(price AS char) FROM items",
+		},
+		{
+			name: "different convert types",
+			a:    "SELECT CONVERT(price, int) AS p FROM items",
+			b:    "SELECT CONVERT(price, char) AS p FROM items",
+		},
+	}
+
+	opts := NormalizeOptions{NormalizeAliases: true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := NormalizeQuery(tt.a, opts), NormalizeQuery(tt.b, opts)
+			if (a == b) != tt.duplicate {
+				t.Errorf("NormalizeQuery(%q) = %q and NormalizeQuery(%q) = %q, want duplicate %v", tt.a, a, tt.b, b, tt.duplicate)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package duplicate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadAllowlist reads a file of normalized queries, or their Hash, that are
+// known to be duplicated on purpose, one per line. Blank lines and lines
+// starting with # are ignored.
+func LoadAllowlist(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading allowlist: %v", err)
+	}
+	defer file.Close()
+
+	allowlist := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		allowlist[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading allowlist: %v", err)
+	}
+	return allowlist, nil
+}
+
+// Suppress removes the allowlisted groups from duplicates and returns how
+// many were removed. Keys are compared without surrounding space, which
+// LoadAllowlist trims but normalized queries may end with.
+func Suppress(duplicates map[string][]QueryResult, allowlist map[string]bool) int {
+	suppressed := 0
+	for key := range duplicates {
+		if allowlist[strings.TrimSpace(key)] || allowlist[Hash(key)] {
+			delete(duplicates, key)
+			suppressed++
+		}
+	}
+	return suppressed
+}
@@ -0,0 +1,42 @@
+package duplicate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSuppressPrintedKeys(t *testing.T) {
+	inList := Normalize("SELECT * FROM users WHERE id IN (1, 2)")
+	plain := Normalize("SELECT * FROM orders WHERE id = 1")
+	hashed := Normalize("SELECT * FROM items WHERE id = 1")
+	kept := Normalize("SELECT * FROM sessions WHERE id = 1")
+
+	// The keys as the tool prints them, one ending with a space
+	content := "# known duplicates\n" + inList + "\n" + plain + "\n\n" + Hash(hashed) + "\n"
+	path := filepath.Join(t.TempDir(), "allowlist.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	allowlist, err := LoadAllowlist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	duplicates := map[string][]QueryResult{inList: nil, plain: nil, hashed: nil, kept: nil}
+	if suppressed := Suppress(duplicates, allowlist); suppressed != 3 {
+		t.Errorf("Suppress removed %d groups, want 3", suppressed)
+	}
+	if _, ok := duplicates[kept]; len(duplicates) != 1 || !ok {
+		t.Errorf("Suppress left %q, want only %q", keys(duplicates), kept)
+	}
+}
+
+// keys returns the keys of groups, in no particular order.
+func keys(groups map[string][]QueryResult) []string {
+	var keys []string
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,435 @@
+package duplicate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval is how often Config.Progress is called during a scan.
+const progressInterval = 200 * time.Millisecond
+
+// channelBuffer is the number of pending jobs and results per worker.
+const channelBuffer = 4
+
+// ioWorkersPerCPU is the number of workers per CPU when Config.NumWorkers is
+// zero. Workers mostly wait for files to be read, so more of them than CPUs
+// keep the disk busy, while acquireCPU lets at most one per CPU analyze.
+const ioWorkersPerCPU = 4
+
+// maxContextLines caps Config.ContextLines to keep results small.
+const maxContextLines = 20
+
+// Scan is the outcome of ProcessFiles.
+type Scan struct {
+	// Groups holds every query found, keyed by normalized form.
+	Groups map[string][]QueryResult
+	// Errors has one entry per file that could not be analyzed.
+	Errors []error
+	// Ignored is the number of queries left out of Groups because they
+	// are marked with a dqf:ignore comment.
+	Ignored int
+	// Analyzed is the number of files analyzed, which is less than the
+	// number of files given when the scan was interrupted.
+	Analyzed int
+	// Interrupted is set when the context was canceled before every file
+	// was analyzed.
+	Interrupted bool
+}
+
+// fileResult is what a worker reports for a single file.
+type fileResult struct {
+	queries []QueryResult
+	err     error
+}
+
+func worker(ctx context.Context, jobs <-chan string, results chan<- fileResult, config Config, processed *atomic.Int64, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for path := range jobs {
+		// Drain the remaining jobs without analyzing them once canceled
+		if ctx.Err() != nil {
+			continue
+		}
+		var queries []QueryResult
+		var err error
+		if config.Cache != nil {
+			queries, err = config.Cache.analyze(path, config)
+		} else {
+			queries, err = AnalyzeFile(path, config)
+		}
+		results <- fileResult{queries: queries, err: err}
+		processed.Add(1)
+	}
+}
+
+// reportProgress calls progress with the number of processed files every
+// progressInterval until stop is closed.
+func reportProgress(progress func(done, total int), processed *atomic.Int64, total int, stop <-chan struct{}) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			progress(int(processed.Load()), total)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// ProcessFiles analyzes files concurrently using config.NumWorkers
+// goroutines, or a number suited to the machine if it is zero, and returns
+// every query found, grouped by normalized form, along with the errors of
+// any file that couldn't be read. Results are added to the groups as
+// workers produce them, so the queries of all files are never buffered at
+// once.
+func ProcessFiles(files []string, config Config) *Scan {
+	return ProcessFilesContext(context.Background(), files, config)
+}
+
+// ProcessFilesContext is like ProcessFiles, but stops handing out files once
+// ctx is canceled. The files being analyzed at that point are finished and
+// the queries found so far are returned, with Scan.Interrupted set.
+func ProcessFilesContext(ctx context.Context, files []string, config Config) *Scan {
+	groups := make(map[string][]QueryResult)
+	scan := processFiles(ctx, files, config, func(query QueryResult) {
+		groups[query.Normalized] = append(groups[query.Normalized], query)
+	})
+	scan.Groups = groups
+
+	// Results arrive in whatever order the workers finish, so sort them to
+	// make the output reproducible
+	for _, group := range scan.Groups {
+		sortResults(group)
+	}
+	return scan
+}
+
+// ProcessFilesFunc is like ProcessFilesContext, but passes the duplicated
+// queries to fn as soon as they are found instead of returning every query,
+// so the groups below config.MinCount are the only ones kept in memory. The
+// returned Scan has no Groups.
+//
+// fn is called with a query's normalized form and the occurrences it hasn't
+// been passed before: every occurrence found so far once the query is
+// duplicated, as decided by config.MinCount and config.DistinctFiles, then
+// each further one. Occurrences come in the order files are analyzed, and fn
+// is only called from the calling goroutine. config.Similarity is ignored, as
+// grouping similar queries needs all of them.
+func ProcessFilesFunc(ctx context.Context, files []string, config Config, fn func(normalized string, occurrences []QueryResult)) *Scan {
+	minCount := max(config.MinCount, 1)
+	pending := make(map[string][]QueryResult)
+	reported := make(map[string]bool)
+	return processFiles(ctx, files, config, func(query QueryResult) {
+		key := query.Normalized
+		if reported[key] {
+			fn(key, []QueryResult{query})
+			return
+		}
+
+		group := append(pending[key], query)
+		duplicated := len(group) >= minCount
+		if config.DistinctFiles {
+			duplicated = CountFiles(group) >= minCount
+		}
+		if !duplicated {
+			pending[key] = group
+			return
+		}
+		delete(pending, key)
+		reported[key] = true
+		sortResults(group)
+		fn(key, group)
+	})
+}
+
+// processFiles analyzes files concurrently, see ProcessFilesContext, and
+// passes each query found that isn't ignored to add, from the calling
+// goroutine. The returned Scan has no Groups.
+func processFiles(ctx context.Context, files []string, config Config, add func(QueryResult)) *Scan {
+	// Start workers, never more than there are files and always at least
+	// one so the jobs are consumed
+	numWorkers := config.NumWorkers
+	if numWorkers == 0 {
+		numWorkers = ioWorkersPerCPU * runtime.NumCPU()
+		config.cpuSlots = make(chan struct{}, runtime.NumCPU())
+	}
+	numWorkers = min(numWorkers, len(files))
+
+	// Every worker has at most one file open, so the limit only needs
+	// enforcing when there are more workers than allowed open files
+	maxOpenFiles := config.MaxOpenFiles
+	if maxOpenFiles == 0 {
+		maxOpenFiles = openFilesLimit() / 2
+	}
+	if maxOpenFiles > 0 && maxOpenFiles < numWorkers {
+		config.fileSlots = make(chan struct{}, maxOpenFiles)
+	}
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+
+	// The channels only hold a few entries per worker, so their size doesn't
+	// grow with the number of files
+	jobs := make(chan string, numWorkers*channelBuffer)
+	results := make(chan fileResult, numWorkers*channelBuffer)
+	var wg sync.WaitGroup
+	var processed atomic.Int64
+
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go worker(ctx, jobs, results, config, &processed, &wg)
+	}
+
+	if config.Progress != nil {
+		stop := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			reportProgress(config.Progress, &processed, len(files), stop)
+			close(stopped)
+		}()
+
+		// Report the final count once everything has been collected
+		defer func() {
+			close(stop)
+			<-stopped
+			config.Progress(int(processed.Load()), len(files))
+		}()
+	}
+
+	// Send jobs from a separate goroutine, as the workers block on the
+	// results channel until it is drained below
+	go func() {
+		defer close(jobs)
+		for _, file := range files {
+			select {
+			case jobs <- file:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Wait for workers in a separate goroutine
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Hand results over as they arrive
+	scan := &Scan{}
+	for result := range results {
+		scan.Analyzed++
+		if result.err != nil {
+			scan.Errors = append(scan.Errors, result.err)
+			continue
+		}
+		for _, query := range result.queries {
+			if query.Ignored {
+				scan.Ignored++
+				continue
+			}
+			add(query)
+		}
+	}
+
+	sort.Slice(scan.Errors, func(i, j int) bool {
+		return scan.Errors[i].Error() < scan.Errors[j].Error()
+	})
+
+	scan.Interrupted = scan.Analyzed < len(files)
+	return scan
+}
+
+// sortResults orders results by file and line.
+func sortResults(results []QueryResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].FilePath != results[j].FilePath {
+			return results[i].FilePath < results[j].FilePath
+		}
+		return results[i].Line < results[j].Line
+	})
+}
+
+// AnalyzeFile extracts and normalizes the SQL queries found in the file at
+// path. Files ending in .gz are decompressed first, and very large files
+// are streamed, see streamThreshold.
+func AnalyzeFile(path string, config Config) ([]QueryResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	if config.MaxFileSize > 0 && info.Size() > config.MaxFileSize {
+		config.warnf("skipping %s, its %d bytes exceed the maximum file size", path, info.Size())
+		return nil, nil
+	}
+	if sourcePath(path) == path && info.Size() > streamThreshold {
+		return analyzeStream(path, config)
+	}
+
+	data, ok, err := readFile(path, config)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	if !ok {
+		config.warnf("skipping %s, its decompressed contents exceed the maximum file size", path)
+		return nil, nil
+	}
+
+	release := config.acquireCPU()
+	defer release()
+	text, ok := decodeText(data)
+	if !ok {
+		config.warnf("%s is not valid UTF-8, reading it as Latin-1", path)
+	}
+	text = normalizeLineBreaks(text)
+	matches := extractQueries(path, text, config)
+	return newResults(path, text, matches, 1, config), nil
+}
+
+// readFile returns the contents of the file at path, decompressed if it ends
+// in .gz, holding one of the open file slots while reading it. ok is false
+// when the decompressed contents exceed config.MaxFileSize.
+func readFile(path string, config Config) (data []byte, ok bool, err error) {
+	releaseFile := config.acquireFile()
+	defer releaseFile()
+	if sourcePath(path) != path {
+		return readGzip(path, config.MaxFileSize)
+	}
+	data, err = os.ReadFile(path)
+	return data, true, err
+}
+
+// acquireCPU waits until fewer files than there are CPUs are being analyzed
+// when ProcessFiles picked the number of workers, so the workers reading
+// files don't compete for the CPU. It returns the function to call once the
+// analysis is done.
+func (c Config) acquireCPU() func() {
+	return acquire(c.cpuSlots)
+}
+
+// acquireFile waits until fewer files than Config.MaxOpenFiles are open, and
+// returns the function to call once the file is closed.
+func (c Config) acquireFile() func() {
+	return acquire(c.fileSlots)
+}
+
+// acquire takes one of slots, waiting for one to be free, and returns the
+// function giving it back. A nil slots channel has no limit.
+func acquire(slots chan struct{}) func() {
+	if slots == nil {
+		return func() {}
+	}
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// newResults turns the matches found in text, sorted by offset, into query
+// results. text holds the file from line firstLine on, the whole file unless
+// it is streamed.
+func newResults(path, text string, matches []Match, firstLine int, config Config) []QueryResult {
+	var lines []string
+	contextLines := min(config.ContextLines, maxContextLines)
+	if contextLines > 0 {
+		lines = strings.Split(text, "\n")
+	}
+
+	// The same query found twice at the same line, e.g. by overlapping
+	// matches, is a single occurrence
+	type occurrence struct {
+		line  int
+		query string
+	}
+	seen := make(map[occurrence]bool)
+	markers := strings.Contains(text, ignoreMarker)
+
+	var loops []bool
+	if config.DetectLoops {
+		loops = inLoops(sourcePath(path), text, matches)
+	}
+
+	// Count lines from the previous match, as the matches are sorted
+	line, lineOffset := 1, 0
+	results := make([]QueryResult, 0, len(matches))
+	for i, match := range matches {
+		line += lineBreaks(text, lineOffset, match.Offset)
+		lineOffset = match.Offset
+		result := QueryResult{
+			FilePath: path,
+			Line:     firstLine - 1 + line,
+			Query:    match.Text,
+			InLoop:   loops != nil && loops[i],
+		}
+		if seen[occurrence{result.Line, result.Query}] {
+			continue
+		}
+		seen[occurrence{result.Line, result.Query}] = true
+		result.Normalized = NormalizeQuery(match.Text, config.NormalizeOptions)
+		result.Ignored = markers && ignoreMarked(text, match.Offset, strings.Count(match.Text, "\n"))
+
+		if contextLines > 0 {
+			endLine := line + strings.Count(match.Text, "\n")
+			first, context := sourceContext(lines, line, endLine, contextLines)
+			result.ContextLine, result.Context = firstLine-1+first, context
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// sourceContext returns the lines from n lines before startLine to n lines
+// after endLine, clipped to the file, along with the number of the first
+// returned line.
+func sourceContext(lines []string, startLine, endLine, n int) (int, []string) {
+	first := max(startLine-n, 1)
+	last := min(endLine+n, len(lines))
+
+	context := make([]string, 0, last-first+1)
+	for _, line := range lines[first-1 : last] {
+		context = append(context, strings.TrimRight(line, "\r"))
+	}
+	return first, context
+}
+
+// extractQueries returns the queries of the file at path found by the
+// extractor registered for its extension, ordered by offset.
+func extractQueries(path, text string, config Config) []Match {
+	matches := extractorFor(sourcePath(path), config).Extract(text)
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Offset < matches[j].Offset
+	})
+	return matches
+}
+
+// normalizeLineBreaks turns the lone \r line breaks of old Mac files into
+// \n, so every line ends with \n or \r\n. The text keeps its length, so
+// offsets into the result are valid offsets into text.
+func normalizeLineBreaks(text string) string {
+	if !strings.Contains(text, "\r") {
+		return text
+	}
+	b := []byte(text)
+	for i, c := range b {
+		if c == '\r' && (i+1 == len(b) || b[i+1] != '\n') {
+			b[i] = '\n'
+		}
+	}
+	return string(b)
+}
+
+// lineBreaks returns the number of line breaks in text between the offsets
+// from and to, negative if to is before from. text must have gone through
+// normalizeLineBreaks, so that \r\n and \n both count as one line break.
+func lineBreaks(text string, from, to int) int {
+	if to < from {
+		return -strings.Count(text[to:from], "\n")
+	}
+	return strings.Count(text[from:to], "\n")
+}
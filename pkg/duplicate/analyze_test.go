@@ -0,0 +1,233 @@
+package duplicate
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFiles creates files with the given names and contents in a new
+// temporary directory, and returns the directory.
+func writeFiles(t testing.TB, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestAnalyzeFileDoubleMatch(t *testing.T) {
+	// An extractor finding each statement twice, as one combining two
+	// overlapping extractors does
+	twice := ExtractorFunc(func(text string) []Match {
+		return append(FindSQLStatements(text), FindSQLStatements(text)...)
+	})
+	RegisterExtractor(".twice", twice)
+	t.Cleanup(func() { delete(extractors, ".twice") })
+
+	content := "SELECT * FROM users WHERE id = 1;\n\nSELECT * FROM users WHERE id = 2;\n"
+	if matches := twice.Extract(content); len(matches) != 4 {
+		t.Fatalf("the extractor found %d matches, want 4", len(matches))
+	}
+	dir := writeFiles(t, map[string]string{"queries.twice": content})
+	results, err := AnalyzeFile(filepath.Join(dir, "queries.twice"), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("AnalyzeFile found %d queries, want 2: %+v", len(results), results)
+	}
+	for i, want := range []int{1, 3} {
+		if results[i].Line != want {
+			t.Errorf("query %d is on line %d, want %d", i, results[i].Line, want)
+		}
+	}
+}
+
+func TestProcessFilesWorkers(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.sql": "SELECT * FROM users WHERE id = 1;\n",
+		"b.sql": "SELECT * FROM users WHERE id = 2;\n",
+		"c.sql": "SELECT * FROM orders WHERE id = 3;\n",
+	})
+	paths, err := FindFiles(Config{FolderPath: dir, FileTypes: []string{".sql"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{0, 1, len(paths) + 5} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			done := make(chan *Scan)
+			go func() { done <- ProcessFiles(paths, Config{NumWorkers: workers}) }()
+
+			var scan *Scan
+			select {
+			case scan = <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("ProcessFiles did not return")
+			}
+			if scan.Analyzed != len(paths) || scan.Interrupted {
+				t.Errorf("ProcessFiles analyzed %d of %d files, interrupted %v", scan.Analyzed, len(paths), scan.Interrupted)
+			}
+			if got := len(scan.Groups["select * from users where id = N"]); got != 2 {
+				t.Errorf("found %d occurrences of the users query, want 2", got)
+			}
+		})
+	}
+
+	// No files at all
+	if scan := ProcessFiles(nil, Config{}); scan.Analyzed != 0 || len(scan.Groups) != 0 {
+		t.Errorf("ProcessFiles(nil) = %+v, want an empty scan", scan)
+	}
+}
+
+func TestProcessFilesOrder(t *testing.T) {
+	// Files of different sizes, so workers finish them in varying order
+	files := make(map[string]string)
+	for i := 0; i < 40; i++ {
+		content := strings.Repeat("-- padding\n", (i*7)%13) +
+			"SELECT * FROM users WHERE id = 1;\n" +
+			strings.Repeat("SELECT * FROM orders WHERE id = 2;\n", i%3+1)
+		files[fmt.Sprintf("f%02d.sql", i)] = content
+	}
+	dir := writeFiles(t, files)
+	paths, err := FindFiles(Config{FolderPath: dir, FileTypes: []string{".sql"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first map[string][]QueryResult
+	for run := 0; run < 5; run++ {
+		// Hand the files over in a different order each run
+		rand.New(rand.NewSource(int64(run))).Shuffle(len(paths), func(i, j int) {
+			paths[i], paths[j] = paths[j], paths[i]
+		})
+		scan := ProcessFiles(paths, Config{NumWorkers: 8})
+		for key, group := range scan.Groups {
+			sorted := sort.SliceIsSorted(group, func(i, j int) bool {
+				if group[i].FilePath != group[j].FilePath {
+					return group[i].FilePath < group[j].FilePath
+				}
+				return group[i].Line < group[j].Line
+			})
+			if !sorted {
+				t.Fatalf("run %d: occurrences of %q are not sorted by file and line", run, key)
+			}
+		}
+		if first == nil {
+			first = scan.Groups
+		} else if !reflect.DeepEqual(scan.Groups, first) {
+			t.Fatalf("run %d: groups differ from the first run", run)
+		}
+	}
+}
+
+// corpusDir writes n copies of the corpus files to a temporary directory and
+// returns their paths.
+func corpusDir(b *testing.B, n int) []string {
+	b.Helper()
+	files := make(map[string]string)
+	for path, text := range readCorpus(b) {
+		for i := 0; i < n; i++ {
+			files[fmt.Sprintf("%03d/%s", i, filepath.Base(path))] = text
+		}
+	}
+	dir := writeFiles(b, files)
+	paths, err := FindFiles(Config{FolderPath: dir, FileTypes: []string{".php", ".py", ".java", ".sql", ".log"}})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return paths
+}
+
+func BenchmarkProcessFilesWorkers(b *testing.B) {
+	paths := corpusDir(b, 100)
+	benchmarked := make(map[int]bool)
+	for _, workers := range []int{0, 1, runtime.NumCPU(), 4 * runtime.NumCPU()} {
+		if benchmarked[workers] {
+			continue
+		}
+		benchmarked[workers] = true
+		name := fmt.Sprintf("workers=%d", workers)
+		if workers == 0 {
+			name = "workers=auto"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ProcessFiles(paths, Config{NumWorkers: workers})
+			}
+			b.ReportMetric(float64(b.N*len(paths))/b.Elapsed().Seconds(), "files/s")
+		})
+	}
+}
+
+func BenchmarkProcessFilesMemory(b *testing.B) {
+	// Many small files, so the per-file overhead of the scan, such as the
+	// job and result channels, dominates the allocations
+	for _, n := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("files=%d", n), func(b *testing.B) {
+			files := make(map[string]string, n)
+			for i := 0; i < n; i++ {
+				files[fmt.Sprintf("%02d/f%d.sql", i%100, i)] = fmt.Sprintf("SELECT * FROM t%d WHERE id = 1;\n", i%10)
+			}
+			paths, err := FindFiles(Config{FolderPath: writeFiles(b, files), FileTypes: []string{".sql"}})
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			for i := 0; i < b.N; i++ {
+				ProcessFiles(paths, Config{NumWorkers: 8})
+			}
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(after.TotalAlloc-before.TotalAlloc)/float64(b.N*n), "B/file")
+		})
+	}
+}
+
+func TestAnalyzeFileLineEndings(t *testing.T) {
+	// CRLF, lone CR and LF line endings, some inside queries
+	path := filepath.Join("..", "..", "test-line-endings.php")
+	want := []int{3, 5, 7, 10, 12}
+
+	check := func(t *testing.T) {
+		results, err := AnalyzeFile(path, Config{ContextLines: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lines []int
+		for _, result := range results {
+			lines = append(lines, result.Line)
+			for _, line := range result.Context {
+				if strings.ContainsRune(line, '\r') {
+					t.Errorf("query at line %d has context line %q, want it without CR", result.Line, line)
+				}
+			}
+		}
+		if !reflect.DeepEqual(lines, want) {
+			t.Errorf("AnalyzeFile(%q) found queries at lines %v, want %v", path, lines, want)
+		}
+	}
+
+	t.Run("in memory", check)
+	t.Run("streamed", func(t *testing.T) {
+		setStreamSizes(t, 64, 128, 64)
+		check(t)
+	})
+}
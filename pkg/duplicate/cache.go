@@ -0,0 +1,147 @@
+package duplicate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// cacheVersion is bumped whenever extraction or normalization changes, so
+// caches written by older versions are discarded.
+const cacheVersion = 15
+
+// Cache remembers the queries found in each file, so files that haven't
+// changed since the previous scan don't need to be analyzed again. A file
+// counts as unchanged when its modification time and size are the same. The
+// cache is only reused with the same options affecting analysis.
+type Cache struct {
+	path string
+
+	mu          sync.Mutex
+	Fingerprint string                `json:"fingerprint"`
+	Files       map[string]cacheEntry `json:"files"`
+}
+
+type cacheEntry struct {
+	ModTime time.Time     `json:"mod_time"`
+	Size    int64         `json:"size"`
+	Queries []QueryResult `json:"queries"`
+}
+
+// LoadCache reads the cache stored at path for a scan with config. A missing
+// cache file, or one written with other options, gives an empty cache.
+func LoadCache(path string, config Config) (*Cache, error) {
+	cache := &Cache{path: path, Fingerprint: cacheFingerprint(config), Files: make(map[string]cacheEntry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading cache: %v", err)
+	}
+
+	var stored Cache
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("error parsing cache %s: %v", path, err)
+	}
+	if stored.Fingerprint == cache.Fingerprint && stored.Files != nil {
+		cache.Files = stored.Files
+	}
+	return cache, nil
+}
+
+// Save writes the cache back to the path it was loaded from, dropping the
+// entries of files that no longer exist.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for path := range c.Files {
+		if _, err := os.Stat(path); err != nil {
+			delete(c.Files, path)
+		}
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error writing cache: %v", err)
+	}
+
+	// Write to a temporary file first so an interrupted run can't leave a
+	// truncated cache behind
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("error writing cache: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing cache: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing cache: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing cache: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("error writing cache: %v", err)
+	}
+	return nil
+}
+
+// analyze returns the cached queries of path if it hasn't changed, and
+// analyzes it with AnalyzeFile otherwise.
+func (c *Cache) analyze(path string, config Config) ([]QueryResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	c.mu.Lock()
+	entry, ok := c.Files[path]
+	c.mu.Unlock()
+	if ok && entry.ModTime.Equal(info.ModTime()) && entry.Size == info.Size() {
+		return entry.Queries, nil
+	}
+
+	queries, err := AnalyzeFile(path, config)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.Files[path] = cacheEntry{ModTime: info.ModTime(), Size: info.Size(), Queries: queries}
+	c.mu.Unlock()
+	return queries, nil
+}
+
+// cacheFingerprint identifies the options that change what AnalyzeFile
+// returns.
+func cacheFingerprint(config Config) string {
+	rules := make([]string, len(config.Rules))
+	for i, rule := range config.Rules {
+		rules[i] = rule.Pattern.String() + "\x00" + rule.Replacement
+	}
+
+	key := struct {
+		Version      int
+		Normalize    NormalizeOptions
+		Rules        []string
+		ReplaceRules bool
+		Extractor    string
+		Delimiter    string
+		ContextLines int
+		MaxFileSize  int64
+		DetectLoops  bool
+	}{cacheVersion, config.NormalizeOptions, rules, config.ReplaceBuiltinRules, config.Extractor, config.Delimiter, config.ContextLines, config.MaxFileSize, config.DetectLoops}
+
+	data, _ := json.Marshal(key)
+	return Hash(string(data))
+}
@@ -0,0 +1,45 @@
+package duplicate
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The lists sortColumns sorts: parenthesized lists and SELECT projections.
+var (
+	columnListRe = regexp.MustCompile(`\( ([^()]*, [^()]*) \)`)
+	projectionRe = regexp.MustCompile(`\b(select (?:distinct )?)([^()]*?, [^()]*?)( from\b)`)
+)
+
+// sortColumns sorts the items of comma separated lists in a normalized query
+// so that queries only differing in column order compare equal. Two kinds of
+// lists are sorted: parenthesized lists without nested parentheses, such as
+// INSERT column and VALUES lists but not subqueries, and SELECT projections
+// made of plain columns.
+//
+// This is a heuristic. Function arguments like "coalesce ( a, b )" are sorted
+// too even though their order matters, and projections containing
+// parentheses are left alone rather than split at the wrong comma.
+func sortColumns(query string) string {
+	query = columnListRe.ReplaceAllStringFunc(query, func(list string) string {
+		items := columnListRe.FindStringSubmatch(list)[1]
+		if strings.HasPrefix(items, "select ") {
+			// A subquery, whose projection is sorted below
+			return list
+		}
+		return "( " + sortList(items) + " )"
+	})
+
+	return projectionRe.ReplaceAllStringFunc(query, func(projection string) string {
+		m := projectionRe.FindStringSubmatch(projection)
+		return m[1] + sortList(m[2]) + m[3]
+	})
+}
+
+// sortList sorts the items of a ", " separated list.
+func sortList(list string) string {
+	items := strings.Split(list, ", ")
+	sort.Strings(items)
+	return strings.Join(items, ", ")
+}
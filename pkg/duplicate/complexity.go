@@ -0,0 +1,13 @@
+package duplicate
+
+import "strings"
+
+// Complexity scores how much the duplicates of a normalized query are worth
+// refactoring: a point per 10 characters, 10 per join and 20 per subquery,
+// multiplied by the number of occurrences. A long query joining several
+// tables scores far higher than a repeated SELECT 1.
+func Complexity(normalized string, occurrences int) int {
+	joins := strings.Count(normalized, " join ")
+	subqueries := strings.Count(normalized, "( select ")
+	return (1 + len(normalized)/10 + 10*joins + 20*subqueries) * occurrences
+}
@@ -0,0 +1,229 @@
+package duplicate
+
+import "strings"
+
+// concatPlaceholder stands in for the variables joined into a query.
+const concatPlaceholder = '?'
+
+// joinConcatenations rewrites string literals joined with the PHP . operator
+// or the + operator of most other languages into a single literal, so
+// queries built like "SELECT * FROM users " . "WHERE id = " . $id are seen as
+// a whole. Variables between the literals, and variables interpolated in
+// PHP "..." strings, become ? placeholders.
+//
+// The text keeps its length and every newline stays in place, so offsets
+// into the result are valid offsets into text.
+func joinConcatenations(text string) string {
+	b := []byte(text)
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == '"' || c == '\'' || c == '`':
+			i = joinLiteral(b, i)
+		case c == '#' || c == '/' && i+1 < len(b) && b[i+1] == '/' || c == '-' && i+1 < len(b) && b[i+1] == '-':
+			for i < len(b) && b[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			end := strings.Index(string(b[i+2:]), "*/")
+			if end < 0 {
+				return string(b)
+			}
+			i += end + 3
+		}
+	}
+	return string(b)
+}
+
+// joinLiteral joins the literal starting at start with the literals and
+// variables concatenated to it and returns the position of the closing quote
+// of the joined literal.
+func joinLiteral(b []byte, start int) int {
+	quote := b[start]
+	end := literalEnd(b, start)
+	if end < 0 {
+		return len(b)
+	}
+	if quote == '"' {
+		replaceInterpolations(b, start+1, end)
+	}
+
+	for {
+		next, ok := concatOperand(b, end+1)
+		if !ok {
+			return end
+		}
+
+		// Another literal with the same quotes: drop the quotes and the
+		// operator between the two
+		if b[next] == quote {
+			nextEnd := literalEnd(b, next)
+			if nextEnd < 0 {
+				return len(b)
+			}
+			if quote == '"' {
+				replaceInterpolations(b, next+1, nextEnd)
+			}
+			blank(b, end, next+1)
+			end = nextEnd
+			continue
+		}
+
+		operandEnd := skipOperand(b, next)
+		if operandEnd == next {
+			return end
+		}
+
+		// A variable followed by another literal becomes a placeholder inside
+		// the joined literal
+		if following, ok := concatOperand(b, operandEnd); ok && b[following] == quote {
+			followingEnd := literalEnd(b, following)
+			if followingEnd < 0 {
+				return len(b)
+			}
+			if quote == '"' {
+				replaceInterpolations(b, following+1, followingEnd)
+			}
+			blank(b, end, following+1)
+			b[end] = concatPlaceholder
+			end = followingEnd
+			continue
+		}
+
+		// A trailing variable: close the literal after its placeholder
+		blank(b, end, operandEnd)
+		b[end] = concatPlaceholder
+		b[end+1] = quote
+		return end + 1
+	}
+}
+
+// concatOperand skips a concatenation operator and the whitespace around it,
+// starting at pos, and returns the position of the operand that follows.
+// The PHP . operator is only accepted before a literal or a $variable, as it
+// is member access in most other languages.
+func concatOperand(b []byte, pos int) (int, bool) {
+	pos = skipSpace(b, pos)
+	if pos >= len(b) || b[pos] != '.' && b[pos] != '+' {
+		return 0, false
+	}
+	op := b[pos]
+	if pos+1 < len(b) && (b[pos+1] == op || b[pos+1] == '=') {
+		return 0, false
+	}
+
+	next := skipSpace(b, pos+1)
+	if next >= len(b) {
+		return 0, false
+	}
+	switch c := b[next]; {
+	case c == '"' || c == '\'' || c == '`':
+		return next, true
+	case op == '.':
+		return next, c == '$'
+	default:
+		return next, c == '$' || c == '_' || isIdentByte(c)
+	}
+}
+
+// skipOperand returns the position just after the variable, member access
+// or call expression starting at pos.
+func skipOperand(b []byte, pos int) int {
+	i := pos
+	if i < len(b) && b[i] == '$' {
+		i++
+	}
+	for i < len(b) {
+		switch {
+		case isIdentByte(b[i]):
+			i++
+		case b[i] == '-' && i+1 < len(b) && b[i+1] == '>',
+			b[i] == ':' && i+1 < len(b) && b[i+1] == ':':
+			i += 2
+		case b[i] == '.' && b[pos] != '$' && i+1 < len(b) && isIdentByte(b[i+1]):
+			i++
+		case b[i] == '(' || b[i] == '[':
+			close := matchingBracket(b, i)
+			if close < 0 {
+				return i
+			}
+			i = close + 1
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// matchingBracket returns the position of the bracket closing the one at
+// pos, on the same line, or -1.
+func matchingBracket(b []byte, pos int) int {
+	depth := 0
+	for i := pos; i < len(b) && b[i] != '\n'; i++ {
+		switch b[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// replaceInterpolations replaces the PHP variables interpolated between
+// start and end, such as $id, $user->id or {$row['id']}, with placeholders.
+func replaceInterpolations(b []byte, start, end int) {
+	for i := start; i < end; i++ {
+		switch {
+		case b[i] == '\\':
+			i++
+		case b[i] == '{' && i+1 < end && b[i+1] == '$':
+			close := strings.IndexByte(string(b[i:end]), '}')
+			if close < 0 {
+				return
+			}
+			blank(b, i, i+close+1)
+			b[i] = concatPlaceholder
+			i += close
+		case b[i] == '$' && i+1 < end && (b[i+1] == '_' || b[i+1] >= 'a' && b[i+1] <= 'z' || b[i+1] >= 'A' && b[i+1] <= 'Z'):
+			varEnd := min(skipOperand(b, i), end)
+			blank(b, i, varEnd)
+			b[i] = concatPlaceholder
+			i = varEnd - 1
+		}
+	}
+}
+
+// literalEnd returns the position of the quote closing the literal that
+// starts at start, or -1.
+func literalEnd(b []byte, start int) int {
+	quote := b[start]
+	for i := start + 1; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// blank overwrites b[start:end] with spaces, keeping newlines.
+func blank(b []byte, start, end int) {
+	for i := start; i < end; i++ {
+		if b[i] != '\n' {
+			b[i] = ' '
+		}
+	}
+}
+
+func skipSpace(b []byte, pos int) int {
+	for pos < len(b) && strings.IndexByte(" \t\r\n", b[pos]) >= 0 {
+		pos++
+	}
+	return pos
+}
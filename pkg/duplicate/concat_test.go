@@ -0,0 +1,136 @@
+package duplicate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJoinConcatenations(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "php literals",
+			text: `$q = "SELECT * FROM t " . "WHERE id = 1";`,
+			want: `$q = "SELECT * FROM t      WHERE id = 1";`,
+		},
+		{
+			name: "trailing variable",
+			text: `$q = 'SELECT * FROM t WHERE id = ' . $id;`,
+			want: `$q = 'SELECT * FROM t WHERE id = ?'     ;`,
+		},
+		{
+			name: "variables and calls between literals",
+			text: `$q = "SELECT * FROM t WHERE a = " . $a . " AND b = " . $b->c() . "";`,
+			want: `$q = "SELECT * FROM t WHERE a = ?          AND b = ?              ";`,
+		},
+		{
+			name: "interpolation",
+			text: `$q = "SELECT * FROM t WHERE a = $a AND b = {$row['b']} AND c = $user->c";`,
+			want: `$q = "SELECT * FROM t WHERE a = ?  AND b = ?           AND c = ?       ";`,
+		},
+		{
+			name: "no interpolation in single quotes",
+			text: `'price: $5' . 'x'`,
+			want: `'price: $5     x'`,
+		},
+		{
+			name: "plus operator",
+			text: `q = "SELECT * FROM t WHERE id = " + id + " AND s = 'x'"`,
+			want: `q = "SELECT * FROM t WHERE id = ?          AND s = 'x'"`,
+		},
+		{
+			name: "plus operator and call",
+			text: `q = "SELECT * FROM t WHERE id = " + strconv.Itoa(id)`,
+			want: `q = "SELECT * FROM t WHERE id = ?"                  `,
+		},
+		{
+			name: "escaped quote",
+			text: `"a \" b" . "c"`,
+			want: `"a \" b     c"`,
+		},
+		{
+			name: "across lines",
+			text: "\"SELECT * \"\n  . \"FROM t\"",
+			want: "\"SELECT *  \n     FROM t\"",
+		},
+		{
+			name: "compound assignments",
+			text: `$q .= " WHERE id = 1"; q += "x"`,
+			want: `$q .= " WHERE id = 1"; q += "x"`,
+		},
+		{
+			name: "member access",
+			text: `n = "SELECT 1".length`,
+			want: `n = "SELECT 1".length`,
+		},
+		{
+			name: "comments",
+			text: "// \"a\" . \"b\"\n\"c\" . \"d\"",
+			want: "// \"a\" . \"b\"\n\"c     d\"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinConcatenations(tt.text); got != tt.want {
+				t.Errorf("joinConcatenations(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinConcatenationsFixtures(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{
+			path: "concat.php",
+			want: []string{
+				"select * from users where id = ?",
+				"select * from users where id = ?",
+				"select * from users where id = ?",
+				"select * from users where id = ?",
+				"select * from orders where customer_id = ? and status = S",
+				"select * from items",
+			},
+		},
+		{
+			path: "concat.go",
+			want: []string{
+				"select * from orders where customer_id = ? and status = S",
+				"select * from orders where customer_id = ? and status = S",
+				"select * from orders where id = ?",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path := filepath.Join("testdata", tt.path)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := string(data)
+
+			var got []string
+			for _, match := range extractQueries(path, text, Config{}) {
+				// Go queries start at their opening quote
+				if !strings.HasPrefix(strings.TrimLeft(text[match.Offset:], "\"`"), match.Text[:6]) {
+					t.Errorf("match %q at offset %d, want the offset of its literal", match.Text, match.Offset)
+				}
+				got = append(got, Normalize(match.Text))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractQueries(%q) = %q, want %q", path, got, tt.want)
+			}
+		})
+	}
+}
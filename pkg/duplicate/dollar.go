@@ -0,0 +1,88 @@
+package duplicate
+
+import "strings"
+
+// dollarQuotes returns the [start, end) byte ranges of the PostgreSQL
+// dollar-quoted string literals in text, such as $$body$$ or $fn$body$fn$.
+// A literal only ends at the same tag it started with, and an unterminated
+// one runs to the end of text. RE2 has no backreferences, so this can't be a
+// regular expression like the other literal patterns.
+func dollarQuotes(text string) [][2]int {
+	var spans [][2]int
+	for i := 0; i < len(text); i++ {
+		if text[i] != '$' {
+			continue
+		}
+		tag, ok := dollarTag(text[i:])
+		if !ok {
+			continue
+		}
+
+		end := len(text)
+		if n := strings.Index(text[i+len(tag):], tag); n >= 0 {
+			end = i + len(tag) + n + len(tag)
+		}
+		spans = append(spans, [2]int{i, end})
+		i = end - 1
+	}
+	return spans
+}
+
+// dollarTag returns the opening delimiter at the start of s: $$ or $tag$,
+// where tag follows the rules of an unquoted identifier. Parameters like $1
+// are not delimiters.
+func dollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1], true
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80:
+		case c >= '0' && c <= '9' && i > 1:
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// replaceDollarQuotes replaces every dollar-quoted literal in text with
+// placeholder and returns the literals it replaced.
+func replaceDollarQuotes(text, placeholder string) (string, []string) {
+	spans := dollarQuotes(text)
+	if len(spans) == 0 {
+		return text, nil
+	}
+
+	var b strings.Builder
+	literals := make([]string, len(spans))
+	last := 0
+	for i, span := range spans {
+		b.WriteString(text[last:span[0]])
+		b.WriteString(placeholder)
+		literals[i] = text[span[0]:span[1]]
+		last = span[1]
+	}
+	b.WriteString(text[last:])
+	return b.String(), literals
+}
+
+// maskDollarQuotes blanks out the body of every dollar-quoted literal in
+// text without changing its length, so statement separators and keywords
+// inside function bodies don't affect where queries start and end.
+func maskDollarQuotes(text string) string {
+	spans := dollarQuotes(text)
+	if len(spans) == 0 {
+		return text
+	}
+
+	masked := []byte(text)
+	for _, span := range spans {
+		for i := span[0] + 1; i < span[1]-1; i++ {
+			if masked[i] != '\n' {
+				masked[i] = '_'
+			}
+		}
+	}
+	return string(masked)
+}
@@ -0,0 +1,179 @@
+// Package duplicate finds SQL queries embedded in source files and groups
+// the ones that are duplicated once normalized.
+package duplicate
+
+import (
+	"context"
+	"errors"
+)
+
+// QueryResult is a single SQL query found in a file.
+type QueryResult struct {
+	FilePath   string
+	Line       int
+	Query      string
+	Normalized string
+	// Context holds the source lines around the query, starting at line
+	// ContextLine, when Config.ContextLines is set.
+	Context     []string
+	ContextLine int
+	// InLoop is set when the query appears to be inside a loop body, a
+	// possible N+1 query, and Config.DetectLoops is set. See LoopQueries.
+	InLoop bool
+	// Ignored is set when the query is marked with a dqf:ignore comment,
+	// on its line or on the line before. ProcessFiles leaves such queries
+	// out of the scan's groups.
+	Ignored bool
+}
+
+// Match is a SQL query found in a piece of text along with the byte
+// offset at which it starts.
+type Match struct {
+	Text   string
+	Offset int
+}
+
+// The SQL extractors Config.Extractor can select.
+const (
+	ExtractorTokenizer = "tokenizer"
+	ExtractorRegex     = "regex"
+)
+
+// Config controls which files are scanned and how duplicates are reported.
+// The JSON keys match the command line flags.
+type Config struct {
+	FolderPath    string   `json:"folder"`
+	IgnoreFolders []string `json:"ignore"`
+	FileTypes     []string `json:"type"`
+	// Include, when set, only keeps the files whose path relative to
+	// FolderPath, or whose name, matches one of these globs, such as
+	// "**/migrations/*.sql". Ignored folders stay ignored.
+	Include []string `json:"include"`
+	// NumWorkers is the number of files analyzed concurrently. Zero picks
+	// it automatically, see ioWorkersPerCPU.
+	NumWorkers   int  `json:"workers"`
+	MinCount     int  `json:"min-count"`
+	UseGitignore bool `json:"use-gitignore"`
+	// MaxDepth limits the walk to this many directory levels when
+	// positive: 1 only finds the files directly in FolderPath, 2 also
+	// those of its subdirectories, and so on. Zero means no limit.
+	MaxDepth int `json:"-"`
+	// DistinctFiles counts the files a query appears in rather than its
+	// occurrences when deciding whether it is duplicated.
+	DistinctFiles bool `json:"distinct-files"`
+	// FollowSymlinks makes FindFiles walk symlinked directories.
+	FollowSymlinks bool `json:"follow-symlinks"`
+	// Similarity enables fuzzy grouping with FindSimilar when greater
+	// than zero.
+	Similarity float64 `json:"similarity"`
+	// ContextLines is the number of source lines to keep before and after
+	// each query, up to 20.
+	ContextLines int `json:"context"`
+	// Extractor selects how SQL is found in files other than Go source:
+	// ExtractorTokenizer, the default, or ExtractorRegex.
+	Extractor string `json:"extractor"`
+	// Delimiter is the statement delimiter of ExtractorTokenizer, a
+	// semicolon if empty. DELIMITER lines in files override it.
+	Delimiter string `json:"delimiter"`
+	// DetectLoops sets QueryResult.InLoop, for LoopQueries. It is off by
+	// default as it costs an extra pass over every file.
+	DetectLoops bool `json:"-"`
+	// MaxFileSize skips files larger than this many bytes. Zero means no
+	// limit.
+	MaxFileSize int64 `json:"max-file-size"`
+	// MaxOpenFiles bounds the number of files ProcessFiles reads at once,
+	// whatever the number of workers. Zero uses half the process's limit on
+	// open files, where the OS reports one.
+	MaxOpenFiles int `json:"max-open-files"`
+	// Progress, when set, is called periodically during ProcessFiles with
+	// the number of files analyzed so far.
+	Progress func(done, total int) `json:"-"`
+	// Warnf, when set, receives warnings about files that could only be
+	// partially understood.
+	Warnf func(format string, args ...any) `json:"-"`
+	// Cache, when set, is used by ProcessFiles to skip files that haven't
+	// changed since it was saved, see LoadCache.
+	Cache *Cache `json:"-"`
+	NormalizeOptions
+
+	// cpuSlots bounds how many files are analyzed at once when NumWorkers
+	// is picked automatically, see acquireCPU.
+	cpuSlots chan struct{}
+	// fileSlots bounds how many files are open at once, see acquireFile.
+	fileSlots chan struct{}
+}
+
+func (c Config) warnf(format string, args ...any) {
+	if c.Warnf != nil {
+		c.Warnf(format, args...)
+	}
+}
+
+// Find scans the files selected by config and returns the duplicated
+// queries keyed by their normalized form. If some files could not be read,
+// the duplicates found in the others are returned together with an error
+// joining the per-file errors.
+func Find(config Config) (map[string][]QueryResult, error) {
+	files, err := FindFiles(config)
+	if err != nil {
+		return nil, err
+	}
+
+	scan := ProcessFiles(files, config)
+	return Duplicates(scan.Groups, config), errors.Join(scan.Errors...)
+}
+
+// FindFunc is like Find, but passes the duplicated queries to fn as they are
+// found instead of returning them, see ProcessFilesFunc.
+func FindFunc(config Config, fn func(normalized string, occurrences []QueryResult)) error {
+	files, err := FindFiles(config)
+	if err != nil {
+		return err
+	}
+
+	scan := ProcessFilesFunc(context.Background(), files, config, fn)
+	return errors.Join(scan.Errors...)
+}
+
+// Duplicates reduces the groups returned by ProcessFiles to the duplicated
+// ones, merging similar groups first when config.Similarity is set. With
+// config.DistinctFiles, groups must also appear in at least
+// config.MinCount different files.
+func Duplicates(groups map[string][]QueryResult, config Config) map[string][]QueryResult {
+	var duplicates map[string][]QueryResult
+	if config.Similarity > 0 {
+		duplicates = FindSimilar(groups, config.Similarity, config.MinCount)
+	} else {
+		duplicates = FindDuplicates(groups, config.MinCount)
+	}
+
+	if config.DistinctFiles {
+		for key, results := range duplicates {
+			if CountFiles(results) < config.MinCount {
+				delete(duplicates, key)
+			}
+		}
+	}
+	return duplicates
+}
+
+// CountFiles returns the number of distinct files results come from.
+func CountFiles(results []QueryResult) int {
+	files := make(map[string]bool)
+	for _, result := range results {
+		files[result.FilePath] = true
+	}
+	return len(files)
+}
+
+// FindDuplicates returns the groups, as returned by ProcessFiles, with at
+// least minCount occurrences. groups is left unchanged.
+func FindDuplicates(groups map[string][]QueryResult, minCount int) map[string][]QueryResult {
+	duplicates := make(map[string][]QueryResult)
+	for key, value := range groups {
+		if len(value) >= minCount {
+			duplicates[key] = value
+		}
+	}
+	return duplicates
+}
@@ -0,0 +1,40 @@
+package duplicate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	once := []QueryResult{{FilePath: "a.php", Line: 1}}
+	twice := []QueryResult{{FilePath: "a.php", Line: 2}, {FilePath: "a.php", Line: 3}}
+	thrice := []QueryResult{{FilePath: "a.php", Line: 4}, {FilePath: "b.php", Line: 1}, {FilePath: "c.php", Line: 1}}
+	groups := map[string][]QueryResult{"once": once, "twice": twice, "thrice": thrice}
+
+	tests := []struct {
+		name   string
+		config Config
+		want   map[string][]QueryResult
+	}{
+		{
+			name:   "min count",
+			config: Config{MinCount: 2},
+			want:   map[string][]QueryResult{"twice": twice, "thrice": thrice},
+		},
+		{
+			name:   "distinct files",
+			config: Config{MinCount: 2, DistinctFiles: true},
+			want:   map[string][]QueryResult{"thrice": thrice},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Duplicates(groups, tt.config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Duplicates = %v, want %v", got, tt.want)
+			}
+			if len(groups) != 3 {
+				t.Errorf("Duplicates left %d of the 3 groups it was passed, want them unchanged", len(groups))
+			}
+		})
+	}
+}
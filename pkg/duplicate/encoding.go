@@ -0,0 +1,51 @@
+package duplicate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The byte order marks decodeText recognizes.
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// decodeText converts file contents to a string for scanning. A leading
+// UTF-8 byte order mark is dropped, and contents starting with a UTF-16 one,
+// as Windows tools often write, are decoded from UTF-16. Contents that
+// aren't valid UTF-8 are assumed to be Latin-1, the usual encoding of legacy
+// PHP files, and are converted byte by byte; the returned bool is false when
+// that happens.
+func decodeText(data []byte) (string, bool) {
+	if bytes.HasPrefix(data, utf16LEBOM) {
+		return decodeUTF16(data[len(utf16LEBOM):], binary.LittleEndian), true
+	}
+	if bytes.HasPrefix(data, utf16BEBOM) {
+		return decodeUTF16(data[len(utf16BEBOM):], binary.BigEndian), true
+	}
+
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if utf8.Valid(data) {
+		return string(data), true
+	}
+
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes), false
+}
+
+// decodeUTF16 decodes UTF-16 data in the given byte order. A trailing odd
+// byte is dropped.
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,105 @@
+package duplicate
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+// encodeUTF16 returns s encoded as UTF-16 in the given byte order, after bom.
+func encodeUTF16(s string, order binary.ByteOrder, bom []byte) []byte {
+	units := utf16.Encode([]rune(s))
+	data := make([]byte, len(bom)+2*len(units))
+	copy(data, bom)
+	for i, unit := range units {
+		order.PutUint16(data[len(bom)+2*i:], unit)
+	}
+	return data
+}
+
+func TestDecodeText(t *testing.T) {
+	const query = "SELECT * FROM café WHERE name = '😀'"
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		ok   bool
+	}{
+		{
+			name: "utf-8",
+			data: []byte(query),
+			want: query,
+			ok:   true,
+		},
+		{
+			name: "utf-8 bom",
+			data: append(append([]byte(nil), utf8BOM...), query...),
+			want: query,
+			ok:   true,
+		},
+		{
+			name: "utf-16le bom",
+			data: encodeUTF16(query, binary.LittleEndian, utf16LEBOM),
+			want: query,
+			ok:   true,
+		},
+		{
+			name: "utf-16be bom",
+			data: encodeUTF16(query, binary.BigEndian, utf16BEBOM),
+			want: query,
+			ok:   true,
+		},
+		{
+			name: "odd length utf-16",
+			data: append(encodeUTF16("SELECT 1", binary.LittleEndian, utf16LEBOM), 'x'),
+			want: "SELECT 1",
+			ok:   true,
+		},
+		{
+			name: "bom only",
+			data: utf16BEBOM,
+			want: "",
+			ok:   true,
+		},
+		{
+			// Without a byte order mark UTF-16 isn't recognized, and its
+			// zero bytes are kept
+			name: "utf-16 without bom",
+			data: encodeUTF16("SELECT", binary.LittleEndian, nil),
+			want: "S\x00E\x00L\x00E\x00C\x00T\x00",
+			ok:   true,
+		},
+		{
+			name: "latin-1",
+			data: []byte("SELECT * FROM caf\xe9"),
+			want: query[:len("SELECT * FROM café")],
+			ok:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := decodeText(tt.data)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("decodeText(%q) = %q, %v, want %q, %v", tt.data, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAnalyzeFileUTF16(t *testing.T) {
+	path := filepath.Join("..", "..", "test-utf16.sql")
+	results, err := AnalyzeFile(path, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 {
+		t.Fatalf("AnalyzeFile(%q) found no queries", path)
+	}
+	for _, result := range results {
+		if result.Line < 2 || result.Normalized == "" {
+			t.Errorf("AnalyzeFile(%q) found %+v, want a query below the comment line", path, result)
+		}
+	}
+}
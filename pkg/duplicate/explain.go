@@ -0,0 +1,31 @@
+package duplicate
+
+import "strings"
+
+// Step is a stage of the normalization of a query, see ExplainQuery.
+type Step struct {
+	// Name describes the stage, such as "numbers", or "rule " followed by
+	// the pattern of a custom rule.
+	Name string
+	// Result is the query after the stage.
+	Result string
+}
+
+// markers shows the placeholders NormalizeQuery sets literals aside with
+// while it works on a query.
+var markers = strings.NewReplacer("\x00", "<literal>", "\x01", "<dollar quoted>")
+
+// ExplainQuery normalizes query like NormalizeQuery and returns the stages
+// that changed it, in order, to see why a query is grouped as it is. The
+// last step's Result is the normalized query, unless no stage changed it.
+func ExplainQuery(query string, opts NormalizeOptions) []Step {
+	var steps []Step
+	previous := query
+	normalize(query, opts, func(name, result string) {
+		if result != previous {
+			steps = append(steps, Step{Name: name, Result: markers.Replace(result)})
+			previous = result
+		}
+	})
+	return steps
+}
@@ -0,0 +1,80 @@
+package duplicate
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// sqlQueryRe is the regular expression FindSQLQueries extracts queries with.
+var sqlQueryRe = regexp.MustCompile(`(?i)(?:WITH\s+(?:RECURSIVE\s+)?\w+\s*(?:\([^)]*\)\s*)?AS\s*(?:(?:NOT\s+)?MATERIALIZED\s*)?\([\s\S]+?|` + // CTEs, up to the end of the statement
+	`CALL\s+[\w.]+\s*\([\s\S]*?|` +
+	`SELECT\s+[\s\S]+?(?:FROM[\s\S]+?)?|` +
+	`INSERT\s+INTO[\s\S]+?|` +
+	`UPDATE\s+\w+\s+SET[\s\S]+?|` +
+	`DELETE\s+FROM[\s\S]+?|` +
+	`CREATE\s+(?:TABLE|DATABASE|INDEX)[\s\S]+?|` +
+	`ALTER\s+TABLE[\s\S]+?|` +
+	`DROP\s+(?:TABLE|DATABASE)[\s\S]+?|` +
+	`TRUNCATE\s+TABLE[\s\S]+?)` +
+	`(?:;|$)`) // Match until semicolon or end of string
+
+// FindSQLQueries returns the SQL statements found in text.
+func FindSQLQueries(text string) []Match {
+	masked := maskDollarQuotes(text)
+	locations := sqlQueryRe.FindAllStringIndex(masked, -1)
+
+	// Clean and validate matches
+	var result []Match
+	end := 0
+	for _, loc := range locations {
+		// Skip what an earlier match was extended over
+		if loc[0] < end {
+			continue
+		}
+		end = unquotedEnd(masked, loc[0], loc[1])
+		match := text[loc[0]:end]
+
+		// Clean up the match, keeping track of where it now starts
+		cleaned := strings.TrimSpace(match)
+		offset := loc[0] + len(match) - len(strings.TrimLeftFunc(match, unicode.IsSpace))
+
+		// Basic validation that it looks like a SQL query
+		if len(cleaned) > 0 &&
+			(strings.HasSuffix(cleaned, ";") ||
+				strings.Contains(strings.ToUpper(cleaned), "SELECT") ||
+				strings.Contains(strings.ToUpper(cleaned), "INSERT") ||
+				strings.Contains(strings.ToUpper(cleaned), "UPDATE") ||
+				strings.HasPrefix(strings.ToUpper(cleaned), "CALL")) {
+
+			result = append(result, Match{Text: cleaned, Offset: offset})
+		}
+	}
+	return result
+}
+
+// unquotedEnd returns where the statement matched at text[start:end] really
+// ends. The regular expression stops at the first semicolon, which may be
+// inside a string literal such as 'a;b', in which case the statement goes
+// on to the first semicolon after the literal is closed. Only single quotes
+// are followed, as double quotes usually delimit the host language string.
+func unquotedEnd(text string, start, end int) int {
+	if !strings.HasSuffix(text[start:end], ";") {
+		return end
+	}
+	inLiteral := false
+	for i := start; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '\\' && inLiteral:
+			i++
+		case c == '\'':
+			inLiteral = !inLiteral
+		case c == ';' && !inLiteral:
+			return i + 1
+		case c == '\n' && i >= end:
+			// Give up rather than run on through the rest of the file
+			return end
+		}
+	}
+	return end
+}
@@ -0,0 +1,114 @@
+package duplicate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindSQLQueries(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Match
+	}{
+		{
+			name: "statements on one line and the next",
+			text: "SELECT * FROM a; SELECT * FROM b WHERE x = 'a;b';\nUPDATE c SET d = 1;",
+			want: []Match{
+				{Text: "SELECT * FROM a;", Offset: 0},
+				{Text: "SELECT * FROM b WHERE x = 'a;b';", Offset: 17},
+				{Text: "UPDATE c SET d = 1;", Offset: 50},
+			},
+		},
+		{
+			name: "statements between comments",
+			text: "-- comment\nINSERT INTO t VALUES (1);\n/* x; y */ DELETE FROM t WHERE id = 2;",
+			want: []Match{
+				{Text: "INSERT INTO t VALUES (1);", Offset: 11},
+				{Text: "DELETE FROM t WHERE id = 2;", Offset: 48},
+			},
+		},
+		{
+			name: "no SQL",
+			text: "nothing to see here",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindSQLQueries(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindSQLQueries(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindSQLStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Match
+	}{
+		{
+			name: "statements on one line and the next",
+			text: "SELECT * FROM a; SELECT * FROM b WHERE x = 'a;b';\nUPDATE c SET d = 1;",
+			want: []Match{
+				{Text: "SELECT * FROM a", Offset: 0},
+				{Text: "SELECT * FROM b WHERE x = 'a;b'", Offset: 17},
+				{Text: "UPDATE c SET d = 1", Offset: 50},
+			},
+		},
+		{
+			name: "statements between comments",
+			text: "-- comment\nINSERT INTO t VALUES (1);\n/* x; y */\nDELETE FROM t WHERE id = 2;",
+			want: []Match{
+				{Text: "INSERT INTO t VALUES (1)", Offset: 11},
+				{Text: "DELETE FROM t WHERE id = 2", Offset: 48},
+			},
+		},
+		{
+			name: "strings of host code",
+			text: `$q = "SELECT * FROM users WHERE id = 1"; $r = "update users set a = 1";`,
+			want: []Match{
+				{Text: "SELECT * FROM users WHERE id = 1", Offset: 6},
+				{Text: "update users set a = 1", Offset: 47},
+			},
+		},
+		{
+			name: "semicolons in parentheses",
+			text: "CREATE TABLE t (a int; b int);\nSELECT 1;",
+			want: []Match{
+				{Text: "CREATE TABLE t (a int; b int)", Offset: 0},
+				{Text: "SELECT 1", Offset: 31},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindSQLStatements(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindSQLStatements(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkFindSQLQueries(b *testing.B) {
+	corpus := readCorpus(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, text := range corpus {
+			FindSQLQueries(text)
+		}
+	}
+}
+
+func BenchmarkFindSQLStatements(b *testing.B) {
+	corpus := readCorpus(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, text := range corpus {
+			FindSQLStatements(text)
+		}
+	}
+}
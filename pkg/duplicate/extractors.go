@@ -0,0 +1,92 @@
+package duplicate
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// An Extractor finds the SQL queries in the contents of a file.
+type Extractor interface {
+	Extract(text string) []Match
+}
+
+// ExtractorFunc adapts a function such as FindGoQueries to an Extractor.
+type ExtractorFunc func(text string) []Match
+
+// Extract calls f(text).
+func (f ExtractorFunc) Extract(text string) []Match {
+	return f(text)
+}
+
+// SQLExtractor finds SQL statements in any text, with
+// FindSQLStatementsDelimited, or with FindSQLQueries when Regex is set.
+// String literals joined with the + or PHP . operator are joined first. It
+// is used for the extensions without a registered extractor.
+type SQLExtractor struct {
+	Regex bool
+	// Delimiter ends statements, a semicolon if empty. FindSQLQueries
+	// always uses semicolons.
+	Delimiter string
+}
+
+// Extract returns the SQL statements in text.
+func (e SQLExtractor) Extract(text string) []Match {
+	text = joinConcatenations(text)
+	if e.Regex {
+		return FindSQLQueries(text)
+	}
+	if e.Delimiter == "" {
+		return FindSQLStatements(text)
+	}
+	return FindSQLStatementsDelimited(text, e.Delimiter)
+}
+
+// PHPExtractor is a SQLExtractor that also extracts the queries of heredocs
+// and nowdocs.
+type PHPExtractor struct {
+	SQLExtractor
+}
+
+// Extract returns the SQL statements in text, heredocs included.
+func (e PHPExtractor) Extract(text string) []Match {
+	matches, text := findHeredocQueries(text)
+	return append(matches, e.SQLExtractor.Extract(text)...)
+}
+
+// extractors maps lowercase file extensions to the extractor used for
+// them. The extractors are created per scan, as some depend on the Config.
+var extractors = map[string]func(config Config) Extractor{
+	".go": func(Config) Extractor { return ExtractorFunc(FindGoQueries) },
+	".py": func(Config) Extractor { return ExtractorFunc(FindPythonQueries) },
+	".php": func(config Config) Extractor {
+		return PHPExtractor{newSQLExtractor(config)}
+	},
+	".log": func(config Config) Extractor {
+		return LogExtractor{newSQLExtractor(config)}
+	},
+	".java": func(config Config) Extractor {
+		return JavaExtractor{newSQLExtractor(config)}
+	},
+	".xml": func(Config) Extractor { return ExtractorFunc(FindMyBatisQueries) },
+}
+
+// RegisterExtractor makes e the extractor of the files with extension ext,
+// such as ".rb", replacing the built-in one if there is one. It must be
+// called before scanning, typically from an init function.
+func RegisterExtractor(ext string, e Extractor) {
+	extractors[strings.ToLower(ext)] = func(Config) Extractor { return e }
+}
+
+// extractorFor returns the extractor registered for the extension of path,
+// or the SQLExtractor selected by config.Extractor.
+func extractorFor(path string, config Config) Extractor {
+	if newExtractor, ok := extractors[strings.ToLower(filepath.Ext(path))]; ok {
+		return newExtractor(config)
+	}
+	return newSQLExtractor(config)
+}
+
+// newSQLExtractor returns the SQLExtractor config selects.
+func newSQLExtractor(config Config) SQLExtractor {
+	return SQLExtractor{Regex: config.Extractor == ExtractorRegex, Delimiter: config.Delimiter}
+}
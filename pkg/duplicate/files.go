@@ -0,0 +1,261 @@
+package duplicate
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FindFiles walks config.FolderPath and returns every file matching one of
+// config.FileTypes and, if given, one of config.Include, skipping anything
+// matched by config.IgnoreFolders. With config.FollowSymlinks, symlinked
+// directories are walked too, and each directory is only walked once so
+// symlink cycles end. When FolderPath is a file rather than a folder, that
+// file alone is returned, whatever its type.
+func FindFiles(config Config) ([]string, error) {
+	return FindFilesContext(context.Background(), config)
+}
+
+// FindFilesContext is like FindFiles, but stops walking once ctx is
+// canceled, returning the files found so far and ctx.Err().
+func FindFilesContext(ctx context.Context, config Config) ([]string, error) {
+	// The walked paths, and so the keys of the .gitignore rules, are built
+	// from the cleaned root
+	config.FolderPath = filepath.Clean(config.FolderPath)
+
+	// A file named explicitly is scanned even if -type wouldn't select it
+	if info, err := os.Stat(config.FolderPath); err == nil && info.Mode().IsRegular() {
+		return []string{config.FolderPath}, nil
+	}
+
+	if err := checkIgnores(config.IgnoreFolders); err != nil {
+		return nil, err
+	}
+	includes, err := compileIncludes(config.Include)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	var ignore *gitignore
+	if config.UseGitignore {
+		ignore = newGitignore()
+	}
+	visited := make(map[string]bool)
+
+	var walk func(root, linkPath string) error
+	walk = func(root, linkPath string) error {
+		return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			// Report the files of symlinked directories under the link
+			if linkPath != "" {
+				path = linkPath + strings.TrimPrefix(path, root)
+			}
+
+			if info.IsDir() && config.MaxDepth > 0 && depth(config.FolderPath, path) >= config.MaxDepth {
+				return filepath.SkipDir
+			}
+
+			if matchesIgnore(config.FolderPath, path, config.IgnoreFolders) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if config.FollowSymlinks {
+				if info.Mode()&os.ModeSymlink != 0 {
+					if target, err := os.Stat(path); err == nil && target.IsDir() {
+						return followSymlink(path, visited, walk)
+					}
+				} else if info.IsDir() {
+					real, err := filepath.EvalSymlinks(path)
+					if err != nil {
+						return err
+					}
+					if visited[real] {
+						return filepath.SkipDir
+					}
+					visited[real] = true
+				}
+			}
+
+			if info.IsDir() {
+				if ignore != nil {
+					if path != config.FolderPath && ignore.ignored(path, true) {
+						return filepath.SkipDir
+					}
+					return ignore.load(path)
+				}
+			}
+
+			if ignore != nil && ignore.ignored(path, false) {
+				return nil
+			}
+
+			if !info.IsDir() && matchesFileType(path, config.FileTypes) && matchesInclude(config.FolderPath, path, includes) {
+				files = append(files, path)
+			}
+
+			return nil
+		})
+	}
+
+	err = walk(config.FolderPath, "")
+	return files, err
+}
+
+// SampleFiles returns percent (0-100) of files, picked at random with seed
+// so the same seed and files give the same sample, in their original order.
+// At least one file is kept when files isn't empty.
+func SampleFiles(files []string, percent float64, seed int64) []string {
+	n := int(math.Round(float64(len(files)) * percent / 100))
+	n = min(max(n, 1), len(files))
+	if n == len(files) {
+		return files
+	}
+
+	picked := rand.New(rand.NewSource(seed)).Perm(len(files))[:n]
+	sort.Ints(picked)
+	sample := make([]string, n)
+	for i, index := range picked {
+		sample[i] = files[index]
+	}
+	return sample
+}
+
+// depth returns how many directory levels path is below root.
+func depth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// followSymlink walks the directory the symlink at path points to, unless
+// it has been walked already.
+func followSymlink(path string, visited map[string]bool, walk func(root, linkPath string) error) error {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	if visited[real] {
+		return nil
+	}
+	return walk(real, path)
+}
+
+// ReadFileList reads file paths from r, one per line, keeping those that
+// match one of config.FileTypes. Blank lines are skipped.
+func ReadFileList(r io.Reader, config Config) ([]string, error) {
+	var files []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		path := strings.TrimSpace(scanner.Text())
+		if path != "" && matchesFileType(path, config.FileTypes) {
+			files = append(files, path)
+		}
+	}
+	return files, scanner.Err()
+}
+
+// matchesFileType reports whether path ends with any of the given file
+// types, ignoring case. Compressed files also match the type before their
+// .gz suffix, so .sql selects dump.sql.gz.
+func matchesFileType(path string, fileTypes []string) bool {
+	lower := strings.ToLower(path)
+	inner := sourcePath(lower)
+	for _, fileType := range fileTypes {
+		fileType = strings.ToLower(fileType)
+		if strings.HasSuffix(lower, fileType) || strings.HasSuffix(inner, fileType) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesIgnore reports whether path matches one of the ignore patterns.
+// Patterns use filepath.Match syntax and are tried against both the base
+// name and the path relative to root, so "vendor", "build-*" and
+// "tests/fixtures" all work. The patterns must have been checked with
+// checkIgnores.
+func matchesIgnore(root, path string, patterns []string) bool {
+	name := filepath.Base(path)
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(filepath.FromSlash(pattern), rel); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIgnores returns an error for the first malformed ignore pattern, as
+// filepath.Match would otherwise never match it.
+func checkIgnores(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid ignore pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// compileIncludes converts the include globs into regular expressions. The
+// globs use the gitignore syntax, where "**" matches across directories.
+func compileIncludes(patterns []string) ([]*regexp.Regexp, error) {
+	includes := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile("^" + globToRegexp(filepath.ToSlash(pattern)) + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %v", pattern, err)
+		}
+		includes[i] = re
+	}
+	return includes, nil
+}
+
+// matchesInclude reports whether path matches one of the include patterns,
+// tried against both the base name and the slash separated path relative to
+// root. Every path matches when there are no patterns.
+func matchesInclude(root, path string, includes []*regexp.Regexp) bool {
+	if len(includes) == 0 {
+		return true
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = path
+	}
+	rel = filepath.ToSlash(rel)
+	name := filepath.Base(path)
+
+	for _, re := range includes {
+		if re.MatchString(name) || re.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package duplicate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindFilesSingleFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"users.php":       `<?php $q = "SELECT * FROM users";`,
+		"other.php":       `<?php $q = "SELECT * FROM other";`,
+		"sub/queries.php": `<?php $q = "SELECT * FROM users";`,
+	})
+
+	tests := []struct {
+		name      string
+		fileTypes []string
+	}{
+		{name: "matching type", fileTypes: []string{".php"}},
+		{name: "other type", fileTypes: []string{".sql"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "users.php")
+			files, err := FindFiles(Config{FolderPath: path, FileTypes: tt.fileTypes})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{path}; !reflect.DeepEqual(files, want) {
+				t.Errorf("FindFiles = %q, want %q", files, want)
+			}
+		})
+	}
+}
+
+func TestFindFilesSymlinks(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"src/a.php": "<?php"})
+	shared := writeFiles(t, map[string]string{"b.php": "<?php"})
+
+	// A symlinked directory outside the root, and a cycle back to the root
+	if err := os.Symlink(shared, filepath.Join(dir, "shared")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(dir, filepath.Join(dir, "src", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		follow bool
+		want   []string
+	}{
+		{
+			name: "not followed",
+			want: []string{filepath.Join(dir, "src", "a.php")},
+		},
+		{
+			name:   "followed",
+			follow: true,
+			want:   []string{filepath.Join(dir, "shared", "b.php"), filepath.Join(dir, "src", "a.php")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := FindFiles(Config{FolderPath: dir, FileTypes: []string{".php"}, FollowSymlinks: tt.follow})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(files, tt.want) {
+				t.Errorf("FindFiles = %q, want %q", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindFilesGitignoreRoot(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"src/.gitignore": "gen/\n",
+		"src/a.php":      "<?php",
+		"src/gen/b.php":  "<?php",
+	})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	want := []string{filepath.Join("src", "a.php")}
+	for _, root := range []string{"src", "./src", "src/", "./src/"} {
+		t.Run(root, func(t *testing.T) {
+			files, err := FindFiles(Config{FolderPath: root, FileTypes: []string{".php"}, UseGitignore: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(files, want) {
+				t.Errorf("FindFiles = %q, want %q", files, want)
+			}
+		})
+	}
+}
+
+func TestFindFilesIgnore(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.php":                         "<?php",
+		"tests/fixtures/b.php":          "<?php",
+		"tests/unit/c.php":              "<?php",
+		"src/tests/fixtures/d.php":      "<?php",
+		"generated/e.php":               "<?php",
+		"generated/deep/nested/f.php":   "<?php",
+		"src/generated/g.php":           "<?php",
+		"src/lib/page_cache/h.php":      "<?php",
+		"src/lib/deep/i_cache_test.php": "<?php",
+		"vendor/pkg/j.php":              "<?php",
+		"src/vendor/k.php":              "<?php",
+	})
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{
+			name:     "name at any depth",
+			patterns: []string{"vendor"},
+			want: []string{
+				"a.php", "generated/deep/nested/f.php", "generated/e.php",
+				"src/generated/g.php", "src/lib/deep/i_cache_test.php", "src/lib/page_cache/h.php",
+				"src/tests/fixtures/d.php", "tests/fixtures/b.php", "tests/unit/c.php",
+			},
+		},
+		{
+			name:     "path relative to the root",
+			patterns: []string{"tests/fixtures"},
+			want: []string{
+				"a.php", "generated/deep/nested/f.php", "generated/e.php",
+				"src/generated/g.php", "src/lib/deep/i_cache_test.php", "src/lib/page_cache/h.php",
+				"src/tests/fixtures/d.php", "src/vendor/k.php", "tests/unit/c.php", "vendor/pkg/j.php",
+			},
+		},
+		{
+			name:     "everything below a directory",
+			patterns: []string{"generated/**"},
+			want: []string{
+				"a.php", "src/generated/g.php", "src/lib/deep/i_cache_test.php", "src/lib/page_cache/h.php",
+				"src/tests/fixtures/d.php", "src/vendor/k.php", "tests/fixtures/b.php", "tests/unit/c.php", "vendor/pkg/j.php",
+			},
+		},
+		{
+			name:     "glob at any depth",
+			patterns: []string{"*cache*", "vendor", "tests"},
+			want:     []string{"a.php", "generated/deep/nested/f.php", "generated/e.php", "src/generated/g.php"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := FindFiles(Config{FolderPath: dir, FileTypes: []string{".php"}, IgnoreFolders: tt.patterns})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, file := range files {
+				rel, err := filepath.Rel(dir, file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindFiles ignoring %q = %q, want %q", tt.patterns, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := FindFiles(Config{FolderPath: dir, FileTypes: []string{".php"}, IgnoreFolders: []string{"vendor", "[abc"}}); err == nil {
+		t.Errorf("FindFiles ignoring %q succeeded, want an error", "[abc")
+	}
+}
@@ -0,0 +1,181 @@
+package duplicate
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitignoreRule is a single pattern read from a .gitignore file.
+type gitignoreRule struct {
+	re      *regexp.Regexp // matched against the slash separated path relative to the .gitignore
+	negate  bool           // pattern started with "!" and re-includes matching paths
+	dirOnly bool           // pattern ended with "/" and only matches directories
+}
+
+// gitignore collects the rules of every .gitignore file found while walking
+// a tree, keyed by the directory that contains the file.
+type gitignore struct {
+	rules map[string][]gitignoreRule
+}
+
+func newGitignore() *gitignore {
+	return &gitignore{rules: make(map[string][]gitignoreRule)}
+}
+
+// load parses dir/.gitignore if it exists.
+func (g *gitignore) load(dir string) error {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var rules []gitignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseGitignoreLine(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(rules) > 0 {
+		g.rules[dir] = rules
+	}
+	return nil
+}
+
+// ignored reports whether path is excluded by the loaded rules. Rules from
+// deeper .gitignore files override shallower ones, and within a single file
+// the last matching rule wins, mirroring git's precedence.
+func (g *gitignore) ignored(path string, isDir bool) bool {
+	// Collect the directories above path, deepest first
+	var dirs []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+
+	ignored := false
+	for i := len(dirs) - 1; i >= 0; i-- {
+		rules, ok := g.rules[dirs[i]]
+		if !ok {
+			continue
+		}
+
+		rel, err := filepath.Rel(dirs[i], path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+
+		for _, rule := range rules {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.re.MatchString(rel) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// parseGitignoreLine turns one line of a .gitignore file into a rule. It
+// returns false for blank lines and comments.
+func parseGitignoreLine(line string) (gitignoreRule, bool) {
+	var rule gitignoreRule
+
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to the
+	// directory of the .gitignore, otherwise it matches at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// globToRegexp converts a gitignore style glob into a regular expression.
+// "*" and "?" never match a slash, while "**" matches across directories.
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				if i+2 < len(pattern) && pattern[i+2] == '/' {
+					// "**/" matches zero or more directories
+					b.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					b.WriteString(".*")
+					i++
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package duplicate
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// goQueryCallRe matches the start of a Query, QueryRow or Exec call, with a
+// submatch for the Context suffix.
+var goQueryCallRe = regexp.MustCompile(`\.(?:Query|QueryRow|Exec)(Context)?\s*\(`)
+
+// FindGoQueries returns the SQL passed to Query, QueryRow, Exec and their
+// Context variants in Go source. Both raw `...` and interpreted "..." string
+// literals are understood, including literals joined with +. Calls whose
+// query argument isn't a literal are skipped.
+func FindGoQueries(text string) []Match {
+	var result []Match
+	for _, loc := range goQueryCallRe.FindAllStringSubmatchIndex(text, -1) {
+		pos := loc[1]
+
+		// The Context variants take the context as their first argument
+		if loc[2] >= 0 {
+			if pos = skipGoArgument(text, pos); pos < 0 {
+				continue
+			}
+		}
+
+		query, offset, ok := readGoStringExpr(text, pos)
+		if !ok {
+			continue
+		}
+
+		if query = strings.TrimSpace(query); query != "" {
+			result = append(result, Match{Text: query, Offset: offset})
+		}
+	}
+	return result
+}
+
+// skipGoArgument returns the position just after the comma ending the
+// argument that starts at pos, or -1 if the call ends first.
+func skipGoArgument(text string, pos int) int {
+	depth := 0
+	for i := pos; i < len(text); i++ {
+		switch text[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth == 0 {
+				return -1
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				return i + 1
+			}
+		case '"', '\'', '`':
+			_, end, ok := readGoLiteral(text, i)
+			if !ok {
+				return -1
+			}
+			i = end - 1
+		}
+	}
+	return -1
+}
+
+// readGoStringExpr reads one or more string literals joined with + starting
+// at pos, skipping leading whitespace. Variables joined after the first
+// literal are read as ? placeholders. It returns the concatenated value and
+// the offset of the first literal.
+func readGoStringExpr(text string, pos int) (string, int, bool) {
+	pos = skipGoSpace(text, pos)
+	offset := pos
+
+	var b strings.Builder
+	for {
+		value, end, ok := readGoLiteral(text, pos)
+		switch {
+		case ok && text[pos] != '\'':
+			b.WriteString(value)
+		case b.Len() > 0 && pos < len(text) && (text[pos] == '_' || isIdentByte(text[pos])):
+			// A variable joined to the query becomes a placeholder
+			end = skipOperand([]byte(text), pos)
+			b.WriteByte(concatPlaceholder)
+		default:
+			return "", 0, false
+		}
+
+		next := skipGoSpace(text, end)
+		if next >= len(text) || text[next] != '+' {
+			return b.String(), offset, true
+		}
+		pos = skipGoSpace(text, next+1)
+	}
+}
+
+// readGoLiteral reads the string, raw string or rune literal starting at pos
+// and returns its value and the position just after it.
+func readGoLiteral(text string, pos int) (string, int, bool) {
+	if pos >= len(text) {
+		return "", 0, false
+	}
+
+	quote := text[pos]
+	switch quote {
+	case '`':
+		end := strings.IndexByte(text[pos+1:], '`')
+		if end < 0 {
+			return "", 0, false
+		}
+		return text[pos+1 : pos+1+end], pos + end + 2, true
+	case '"', '\'':
+		for i := pos + 1; i < len(text); i++ {
+			switch text[i] {
+			case '\\':
+				i++
+			case '\n':
+				return "", 0, false
+			case quote:
+				value, err := strconv.Unquote(text[pos : i+1])
+				if err != nil {
+					return "", 0, false
+				}
+				return value, i + 1, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+func skipGoSpace(text string, pos int) int {
+	for pos < len(text) && strings.ContainsRune(" \t\r\n", rune(text[pos])) {
+		pos++
+	}
+	return pos
+}
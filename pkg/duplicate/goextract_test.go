@@ -0,0 +1,102 @@
+package duplicate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindGoQueries(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "interpreted string",
+			text: `db.Query("SELECT * FROM t WHERE name = \"x\"\n")`,
+			want: []string{`SELECT * FROM t WHERE name = "x"`},
+		},
+		{
+			name: "raw string",
+			text: "db.Exec(`\n\tUPDATE t\n\tSET a = 1`)",
+			want: []string{"UPDATE t\n\tSET a = 1"},
+		},
+		{
+			name: "literals joined with +",
+			text: "db.QueryRow(\"SELECT * \" +\n\t`FROM t ` + \"WHERE id = ?\", id)",
+			want: []string{"SELECT * FROM t WHERE id = ?"},
+		},
+		{
+			name: "context variants",
+			text: `db.QueryContext(ctx, "SELECT 1"); db.QueryRowContext(ctx, "SELECT 2"); db.ExecContext(ctx, "SELECT 3")`,
+			want: []string{"SELECT 1", "SELECT 2", "SELECT 3"},
+		},
+		{
+			name: "context expression with commas",
+			text: `db.ExecContext(context.WithValue(ctx, "k,)", f(a, b)), "DELETE FROM t")`,
+			want: []string{"DELETE FROM t"},
+		},
+		{
+			name: "space before the arguments",
+			text: `db.Query ( "SELECT 1" )`,
+			want: []string{"SELECT 1"},
+		},
+		{
+			name: "non literal arguments",
+			text: `q := r.URL.Query(); db.Query(query); db.Exec(build(q)); db.QueryContext(ctx, query)`,
+		},
+		{
+			name: "rune literal",
+			text: `db.Query('x')`,
+		},
+		{
+			name: "missing query after the context",
+			text: `db.QueryContext(ctx)`,
+		},
+		{
+			name: "empty query",
+			text: `db.Exec(" ")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, match := range FindGoQueries(tt.text) {
+				got = append(got, match.Text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindGoQueries(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindGoQueriesFixture(t *testing.T) {
+	path := filepath.Join("testdata", "queries.go")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+
+	var got []string
+	for _, match := range extractQueries(path, text, Config{}) {
+		if quote := text[match.Offset]; quote != '"' && quote != '`' {
+			t.Errorf("match %q at offset %d, want the offset of its first literal", match.Text, match.Offset)
+		}
+		got = append(got, Normalize(match.Text))
+	}
+	want := []string{
+		"select * from users where id = ?",
+		"select * from users where id = ?",
+		"select * from users where id = ?",
+		"delete from sessions where user_id = ?",
+		"select id, total from orders where status = S",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("extractQueries(%q) = %q, want %q", path, got, want)
+	}
+}
@@ -0,0 +1,52 @@
+package duplicate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// gzipSuffix marks compressed files, which are scanned as the file type
+// before it, such as .sql for dump.sql.gz.
+const gzipSuffix = ".gz"
+
+// sourcePath returns path without its .gz suffix, the path whose extension
+// tells how the contents are scanned.
+func sourcePath(path string) string {
+	if strings.HasSuffix(strings.ToLower(path), gzipSuffix) {
+		return path[:len(path)-len(gzipSuffix)]
+	}
+	return path
+}
+
+// readGzip returns the decompressed contents of the gzip file at path. When
+// maxSize is positive, contents larger than maxSize bytes are not read and
+// ok is false.
+func readGzip(path string, maxSize int64) (data []byte, ok bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %v", path, err)
+	}
+	defer r.Close()
+
+	var src io.Reader = r
+	if maxSize > 0 {
+		src = io.LimitReader(r, maxSize+1)
+	}
+	data, err = io.ReadAll(src)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %v", path, err)
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, false, nil
+	}
+	return data, true, nil
+}
@@ -0,0 +1,70 @@
+package duplicate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeFileGzip(t *testing.T) {
+	gzPath := filepath.Join("..", "..", "test.sql.gz")
+	f, err := os.Open(gzPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainPath := filepath.Join(t.TempDir(), "test.sql")
+	if err := os.WriteFile(plainPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{ContextLines: 1}
+	want, err := AnalyzeFile(plainPath, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) == 0 {
+		t.Fatalf("AnalyzeFile(%q) found no queries", plainPath)
+	}
+	got, err := AnalyzeFile(gzPath, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range got {
+		got[i].FilePath = plainPath
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AnalyzeFile(%q) = %+v, want the queries of the plain file %+v", gzPath, got, want)
+	}
+}
+
+func TestAnalyzeFileCorruptGzip(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "test.sql.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := writeFiles(t, map[string]string{
+		"plain.sql.gz":     "SELECT * FROM users WHERE id = 1;\n",
+		"truncated.sql.gz": string(data[:len(data)/2]),
+	})
+
+	for _, name := range []string{"plain.sql.gz", "truncated.sql.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if results, err := AnalyzeFile(path, Config{}); err == nil {
+				t.Errorf("AnalyzeFile(%q) = %+v, want an error", path, results)
+			}
+		})
+	}
+}
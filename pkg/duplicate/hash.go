@@ -0,0 +1,18 @@
+package duplicate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// hashLength is the number of hex characters kept from the SHA-256 digest.
+const hashLength = 12
+
+// Hash returns a short fingerprint of a normalized query: the first 12 hex
+// characters of its SHA-256 digest. It only depends on the normalized text,
+// so it is stable across runs and platforms and can be used as a key for
+// tracking duplicates over time.
+func Hash(normalized string) string {
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])[:hashLength]
+}
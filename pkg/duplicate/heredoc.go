@@ -0,0 +1,85 @@
+package duplicate
+
+import (
+	"regexp"
+	"strings"
+)
+
+// heredocOpenRe matches the opening of a heredoc or nowdoc, with the label
+// in the first submatch for "LABEL", the second for 'LABEL' (a nowdoc) and
+// the third for a bare LABEL.
+var heredocOpenRe = regexp.MustCompile(`<<<[ \t]*(?:"([A-Za-z_]\w*)"|'([A-Za-z_]\w*)'|([A-Za-z_]\w*))\r?\n`)
+
+// findHeredocQueries returns the PHP heredocs (<<<SQL) and nowdocs
+// (<<<'SQL') of text whose body is a SQL statement, with the variables
+// interpolated in heredocs read as ? placeholders. It also returns text with
+// every heredoc and nowdoc body blanked out, keeping offsets and newlines, so
+// the other extractors don't find the same queries again or stop at a
+// semicolon inside a body.
+func findHeredocQueries(text string) ([]Match, string) {
+	b := []byte(text)
+	var result []Match
+	for pos := 0; pos < len(text); {
+		loc := heredocOpenRe.FindStringSubmatchIndex(text[pos:])
+		if loc == nil {
+			break
+		}
+		nowdoc := loc[4] >= 0
+		var label string
+		for g := 1; g <= 3; g++ {
+			if loc[2*g] >= 0 {
+				label = text[pos+loc[2*g] : pos+loc[2*g+1]]
+			}
+		}
+
+		bodyStart := pos + loc[1]
+		bodyEnd, closeEnd := heredocEnd(text, bodyStart, label)
+		if bodyEnd < 0 {
+			break
+		}
+
+		body := make([]byte, bodyEnd-bodyStart)
+		copy(body, b[bodyStart:bodyEnd])
+		if !nowdoc {
+			replaceInterpolations(body, 0, len(body))
+		}
+
+		query := strings.TrimSpace(string(body))
+		if statementStartRe.MatchString(query[:min(len(query), maxKeywordLength)]) {
+			offset := bodyStart + len(body) - len(strings.TrimLeft(string(body), " \t\r\n"))
+			result = append(result, Match{Text: query, Offset: offset})
+		}
+
+		blank(b, bodyStart, bodyEnd)
+		pos = closeEnd
+	}
+	return result, string(b)
+}
+
+// heredocEnd finds the line closing the heredoc body starting at start:
+// the label, optionally indented, not followed by an identifier character.
+// It returns the end of the body, excluding the newline before the closing
+// line, and the end of the label, or -1 if the heredoc isn't closed.
+func heredocEnd(text string, start int, label string) (int, int) {
+	for lineStart := start; lineStart <= len(text); {
+		line := text[lineStart:]
+		if n := strings.IndexByte(line, '\n'); n >= 0 {
+			line = line[:n]
+		}
+
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(trimmed, label) && (len(trimmed) == len(label) || !isIdentByte(trimmed[len(label)]) && trimmed[len(label)] < 0x80) {
+			bodyEnd := max(lineStart-1, start)
+			if bodyEnd > start && text[bodyEnd-1] == '\r' {
+				bodyEnd--
+			}
+			return bodyEnd, lineStart + len(line) - len(trimmed) + len(label)
+		}
+
+		if lineStart+len(line) >= len(text) {
+			break
+		}
+		lineStart += len(line) + 1
+	}
+	return -1, -1
+}
@@ -0,0 +1,118 @@
+package duplicate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindHeredocQueries(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "heredoc",
+			text: "$q = <<<SQL\nSELECT * FROM users\nWHERE id = $id AND name = {$user->name};\nSQL;\n",
+			want: []string{"SELECT * FROM users\nWHERE id = ?   AND name = ?            ;"},
+		},
+		{
+			name: "quoted heredoc label",
+			text: "$q = <<<\"SQL\"\nDELETE FROM t WHERE id = $id\nSQL;\n",
+			want: []string{"DELETE FROM t WHERE id = ?"},
+		},
+		{
+			name: "nowdoc",
+			text: "$q = <<<'SQL'\nSELECT * FROM users WHERE id = $id\nSQL;\n",
+			want: []string{"SELECT * FROM users WHERE id = $id"},
+		},
+		{
+			name: "indented closing label",
+			text: "    $q = <<<SQL\n        UPDATE t SET a = 1\n        SQL;\n",
+			want: []string{"UPDATE t SET a = 1"},
+		},
+		{
+			name: "label inside the body",
+			text: "$q = <<<SQL\nSELECT SQLITE_VERSION()\nSQL\n",
+			want: []string{"SELECT SQLITE_VERSION()"},
+		},
+		{
+			name: "crlf",
+			text: "$q = <<<SQL\r\nSELECT 1\r\nSQL;\r\n",
+			want: []string{"SELECT 1"},
+		},
+		{
+			name: "not SQL",
+			text: "$s = <<<EOT\nHello $name\nEOT;\n",
+		},
+		{
+			name: "unterminated",
+			text: "$q = <<<SQL\nSELECT * FROM users;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, blanked := findHeredocQueries(tt.text)
+			var got []string
+			for _, match := range matches {
+				got = append(got, match.Text)
+				if !strings.HasPrefix(tt.text[match.Offset:], strings.Fields(match.Text)[0]) {
+					t.Errorf("match %q at offset %d, want the offset of its first word", match.Text, match.Offset)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findHeredocQueries(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+
+			if len(blanked) != len(tt.text) {
+				t.Fatalf("findHeredocQueries(%q) blanked text to %q, changing its length", tt.text, blanked)
+			}
+			for i := range blanked {
+				if (blanked[i] == '\n') != (tt.text[i] == '\n') {
+					t.Fatalf("findHeredocQueries(%q) blanked text to %q, moving line breaks", tt.text, blanked)
+				}
+			}
+			if tt.name == "unterminated" && blanked != tt.text {
+				t.Errorf("findHeredocQueries(%q) blanked text to %q, want it unchanged", tt.text, blanked)
+			}
+		})
+	}
+}
+
+func TestAnalyzeFileHeredocLines(t *testing.T) {
+	content := "<?php\n" +
+		"$a = <<<SQL\n" +
+		"    SELECT * FROM users\n" +
+		"    WHERE id = $id;\n" +
+		"    SQL;\n" +
+		"$b = <<<'SQL'\n" +
+		"\n" +
+		"DELETE FROM sessions; -- a semicolon in a nowdoc\n" +
+		"SQL;\n" +
+		"$c = \"UPDATE users SET name = 'x'\";\n"
+	path := filepath.Join(t.TempDir(), "heredoc.php")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := AnalyzeFile(path, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[int]string)
+	for _, result := range results {
+		got[result.Line] = result.Normalized
+	}
+	want := map[int]string{
+		3:  "select * from users where id = ?",
+		8:  "delete from sessions",
+		10: "update users set name = S",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AnalyzeFile found %q by line, want %q", got, want)
+	}
+}
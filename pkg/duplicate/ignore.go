@@ -0,0 +1,42 @@
+package duplicate
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ignoreMarker is the comment directive that excludes a query from the
+// results, like the nolint comments of linters.
+const ignoreMarker = "dqf:ignore"
+
+// The regular expressions recognizing the ignore directive, compiled once.
+var (
+	// ignoreRe matches the directive in a --, //, # or /* comment.
+	ignoreRe = regexp.MustCompile(`(?:--|//|#|/\*)\s*` + ignoreMarker + `\b`)
+	// ignoreLineRe matches a line holding nothing but such a comment.
+	ignoreLineRe = regexp.MustCompile(`^\s*(?:--|//|#|/\*)\s*` + ignoreMarker + `\b`)
+)
+
+// ignoreMarked reports whether the query at offset, spanning lines more
+// lines, carries the ignore directive: in a comment on one of its lines, or
+// in a comment on a line of its own just before it.
+func ignoreMarked(text string, offset, lines int) bool {
+	start := strings.LastIndexByte(text[:offset], '\n') + 1
+	end := start
+	for i := 0; i <= lines && end < len(text); i++ {
+		n := strings.IndexByte(text[end:], '\n')
+		if n < 0 {
+			end = len(text)
+			break
+		}
+		end += n + 1
+	}
+	if ignoreRe.MatchString(text[start:end]) {
+		return true
+	}
+	if start == 0 {
+		return false
+	}
+	previous := text[strings.LastIndexByte(text[:start-1], '\n')+1 : start-1]
+	return ignoreLineRe.MatchString(previous)
+}
@@ -0,0 +1,73 @@
+package duplicate
+
+import "strings"
+
+// javaEscapes are the escape sequences of Java text blocks that can appear
+// in SQL. A backslash at the end of a line joins it with the next one.
+var javaEscapes = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\'`, `'`, `\n`, "\n", `\t`, "\t", "\\\n", "", `\s`, " ")
+
+// JavaExtractor is a SQLExtractor that also extracts the queries of Java
+// text blocks ("""). Ordinary string literals, joined with + or not, such as
+// the arguments of @Query annotations and String constants, are found by
+// the SQLExtractor.
+type JavaExtractor struct {
+	SQLExtractor
+}
+
+// Extract returns the SQL statements in text, text blocks included.
+func (e JavaExtractor) Extract(text string) []Match {
+	matches, text := findTextBlockQueries(text)
+	return append(matches, e.SQLExtractor.Extract(text)...)
+}
+
+// findTextBlockQueries returns the Java text blocks of text whose content is
+// a SQL statement. It also returns text with the content of every text block
+// blanked out, keeping offsets and newlines, like findHeredocQueries.
+func findTextBlockQueries(text string) ([]Match, string) {
+	b := []byte(text)
+	var result []Match
+	for pos := 0; pos < len(text); {
+		n := strings.Index(text[pos:], `"""`)
+		if n < 0 {
+			break
+		}
+		open := pos + n + 3
+
+		// The opening delimiter is followed by nothing but a line break
+		lineEnd := strings.IndexByte(text[open:], '\n')
+		if lineEnd < 0 || strings.TrimSpace(text[open:open+lineEnd]) != "" {
+			pos = open
+			continue
+		}
+		bodyStart := open + lineEnd + 1
+		bodyEnd := textBlockEnd(text, bodyStart)
+		if bodyEnd < 0 {
+			break
+		}
+
+		body := text[bodyStart:bodyEnd]
+		query := strings.TrimSpace(javaEscapes.Replace(body))
+		if statementStartRe.MatchString(query[:min(len(query), maxKeywordLength)]) {
+			offset := bodyStart + len(body) - len(strings.TrimLeft(body, " \t\r\n"))
+			result = append(result, Match{Text: query, Offset: offset})
+		}
+
+		blank(b, bodyStart, bodyEnd)
+		pos = bodyEnd + 3
+	}
+	return result, string(b)
+}
+
+// textBlockEnd returns the position of the """ closing the text block whose
+// content starts at start, or -1 if it isn't closed.
+func textBlockEnd(text string, start int) int {
+	for i := start; i < len(text); i++ {
+		switch {
+		case text[i] == '\\':
+			i++
+		case strings.HasPrefix(text[i:], `"""`):
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,76 @@
+package duplicate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindTextBlockQueries(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "text block",
+			text: "@Query(value = \"\"\"\n        SELECT *\n        FROM orders\n        \"\"\")",
+			want: []string{"SELECT *\n        FROM orders"},
+		},
+		{
+			name: "escapes",
+			text: "String q = \"\"\"\n    SELECT * FROM t \\\n    WHERE a = \\\"x\\\"\\s\n    \"\"\";",
+			want: []string{"SELECT * FROM t     WHERE a = \"x\""},
+		},
+		{
+			name: "escaped delimiter",
+			text: "String q = \"\"\"\n    SELECT '\\\"\"\"' FROM t\n    \"\"\";",
+			want: []string{`SELECT '"""' FROM t`},
+		},
+		{
+			name: "not SQL",
+			text: "String s = \"\"\"\n    Hello\n    \"\"\";",
+		},
+		{
+			name: "delimiter not followed by a line break",
+			text: `String s = """SELECT 1""";`,
+		},
+		{
+			name: "unterminated",
+			text: "String s = \"\"\"\n    SELECT * FROM t\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, blanked := findTextBlockQueries(tt.text)
+			var got []string
+			for _, match := range matches {
+				got = append(got, match.Text)
+				if !strings.HasPrefix(tt.text[match.Offset:], strings.Fields(match.Text)[0]) {
+					t.Errorf("match %q at offset %d, want the offset of its first word", match.Text, match.Offset)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findTextBlockQueries(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if len(blanked) != len(tt.text) || strings.Count(blanked, "\n") != strings.Count(tt.text, "\n") {
+				t.Errorf("findTextBlockQueries(%q) blanked text to %q, changing offsets or lines", tt.text, blanked)
+			}
+		})
+	}
+}
+
+func TestJavaExtractor(t *testing.T) {
+	text := "String a = \"SELECT * FROM t WHERE id = 1\";\n" +
+		"String b = \"\"\"\n    SELECT * FROM t\n    WHERE id = 2\n    \"\"\";\n"
+
+	var got []string
+	for _, match := range (JavaExtractor{}).Extract(text) {
+		got = append(got, Normalize(match.Text))
+	}
+	want := []string{"select * from t where id = N", "select * from t where id = N"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JavaExtractor.Extract(%q) = %q, want %q", text, got, want)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package duplicate
+
+// openFilesLimit returns 0 where the limit on open files can't be read, so
+// only the number of workers bounds the files open at once.
+func openFilesLimit() int {
+	return 0
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package duplicate
+
+import (
+	"math"
+	"syscall"
+)
+
+// openFilesLimit returns the soft limit on the number of files the process
+// can open, as set by ulimit -n, or 0 if there is none.
+func openFilesLimit() int {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil || limit.Cur > math.MaxInt32 {
+		return 0
+	}
+	return int(limit.Cur)
+}
@@ -0,0 +1,80 @@
+package duplicate
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The regular expressions recognizing the lines of SQL logs, compiled once.
+var (
+	// logMetadataRe matches the lines of a log that never hold a query:
+	// the # Time, # User@Host and # Query_time headers of MySQL slow query
+	// logs, the SET timestamp and use lines before each query, and the
+	// banner mysqld writes when it starts.
+	logMetadataRe = regexp.MustCompile(`(?i)^(?:#|set timestamp\s*=|use\s+\S+;\s*$|/\S*mysqld\S*, version:|tcp port:|time\s+id\s+command\s+argument)`)
+	// logGeneralRe matches the prefix of a MySQL general log line: an
+	// optional timestamp, the connection id and the command, such as
+	// Query or Connect, followed by a tab.
+	logGeneralRe = regexp.MustCompile(`^(?:\d{4}-\d{2}-\d{2}T[\d:.]+Z?|\d{6}\s+[\d:]+)?\s+\d+\s+([A-Z][a-z]+(?: [A-Z][A-Za-z]+)?)\t`)
+	// logPostgresRe matches the prefix of a PostgreSQL log line up to the
+	// statement, as logged by log_statement or log_min_duration_statement.
+	logPostgresRe = regexp.MustCompile(`^.*?\b(?:LOG|STATEMENT):\s+(?:duration:\s*[\d.]+\s*ms\s+)?(?:(?:statement|execute\s+[^:]*):\s+)?`)
+	// logTimestampRe matches any other timestamp, in brackets or not, at
+	// the start of a line.
+	logTimestampRe = regexp.MustCompile(`^\[?\d{4}-\d{2}-\d{2}[ T][\d:.,]+(?:Z|[+-]\d{2}:?\d{2})?\]?\s*`)
+)
+
+// LogExtractor finds the queries of SQL log files, such as MySQL slow query
+// and general logs and PostgreSQL logs. The metadata of each entry, like
+// timestamps, connection ids and # headers, is skipped, and the rest of the
+// entry is searched for statements with the embedded SQLExtractor. Entries
+// are searched separately, so a query without a terminating semicolon
+// doesn't run into the next one.
+type LogExtractor struct {
+	SQLExtractor
+}
+
+// Extract returns the SQL statements logged in text.
+func (e LogExtractor) Extract(text string) []Match {
+	var result []Match
+	entryStart := -1
+	flush := func(end int) {
+		if entryStart >= 0 {
+			for _, match := range e.SQLExtractor.Extract(text[entryStart:end]) {
+				match.Offset += entryStart
+				result = append(result, match)
+			}
+		}
+		entryStart = -1
+	}
+
+	for lineStart := 0; lineStart < len(text); {
+		lineEnd := len(text)
+		if n := strings.IndexByte(text[lineStart:], '\n'); n >= 0 {
+			lineEnd = lineStart + n + 1
+		}
+		line := text[lineStart:lineEnd]
+
+		switch {
+		case logMetadataRe.MatchString(line):
+			flush(lineStart)
+		case logGeneralRe.MatchString(line):
+			flush(lineStart)
+			if m := logGeneralRe.FindStringSubmatchIndex(line); line[m[2]:m[3]] == "Query" || line[m[2]:m[3]] == "Execute" {
+				entryStart = lineStart + m[1]
+			}
+		case logPostgresRe.MatchString(line):
+			flush(lineStart)
+			entryStart = lineStart + len(logPostgresRe.FindString(line))
+		case logTimestampRe.MatchString(line):
+			flush(lineStart)
+			entryStart = lineStart + len(logTimestampRe.FindString(line))
+		case entryStart < 0:
+			// A continuation line, or the query of a slow log entry
+			entryStart = lineStart
+		}
+		lineStart = lineEnd
+	}
+	flush(len(text))
+	return result
+}
@@ -0,0 +1,84 @@
+package duplicate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLogExtractor(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "slow query log",
+			text: "# Time: 2024-05-01T10:00:00.123456Z\n" +
+				"# User@Host: app[app] @ localhost []  Id:    12\n" +
+				"# Query_time: 2.000123  Lock_time: 0.000010 Rows_sent: 1  Rows_examined: 100000\n" +
+				"use shop;\n" +
+				"SET timestamp=1714557600;\n" +
+				"SELECT p.name, SUM(i.quantity)\n" +
+				"FROM order_items i\n" +
+				"GROUP BY p.name;\n" +
+				"# Time: 2024-05-01T10:00:05.000001Z\n" +
+				"SET timestamp=1714557605;\n" +
+				"SELECT 1\n",
+			want: []string{"SELECT p.name, SUM(i.quantity)\nFROM order_items i\nGROUP BY p.name", "SELECT 1"},
+		},
+		{
+			name: "general log",
+			text: "/usr/sbin/mysqld, Version: 8.0.36 (MySQL Community Server - GPL). started with:\n" +
+				"Tcp port: 3306  Unix socket: /var/run/mysqld/mysqld.sock\n" +
+				"Time                 Id Command    Argument\n" +
+				"2024-05-01T10:00:00.000000Z\t   12 Connect\tapp@localhost on shop using TCP/IP\n" +
+				"2024-05-01T10:00:00.100000Z\t   12 Query\tSELECT * FROM users WHERE id = 1\n" +
+				"2024-05-01T10:00:00.200000Z\t   12 Query\tSELECT *\n" +
+				"FROM orders\n" +
+				"WHERE id = 2\n" +
+				"2024-05-01T10:00:00.300000Z\t   12 Prepare\tSELECT * FROM items WHERE id = ?\n" +
+				"2024-05-01T10:00:00.400000Z\t   12 Execute\tSELECT * FROM items WHERE id = 3\n" +
+				"2024-05-01T10:00:00.500000Z\t   12 Init DB\tshop\n" +
+				"2024-05-01T10:00:00.600000Z\t   12 Quit\t\n",
+			want: []string{"SELECT * FROM users WHERE id = 1", "SELECT *\nFROM orders\nWHERE id = 2", "SELECT * FROM items WHERE id = 3"},
+		},
+		{
+			name: "general log of older servers",
+			text: "240501 10:00:00\t   12 Query\tUPDATE users SET a = 1\n" +
+				"\t   13 Query\tDELETE FROM t\n",
+			want: []string{"UPDATE users SET a = 1", "DELETE FROM t"},
+		},
+		{
+			name: "postgresql log",
+			text: "2024-05-01 10:00:00.123 UTC [1234] LOG:  duration: 12.345 ms  statement: SELECT * FROM users WHERE id = 1\n" +
+				"2024-05-01 10:00:01.000 UTC [1234] LOG:  statement: UPDATE users\n" +
+				"\tSET name = 'x'\n" +
+				"\tWHERE id = 2\n" +
+				"2024-05-01 10:00:02.000 UTC [1234] LOG:  execute <unnamed>: SELECT * FROM t WHERE id = $1\n" +
+				"2024-05-01 10:00:02.000 UTC [1234] DETAIL:  parameters: $1 = '5'\n" +
+				"2024-05-01 10:00:03.000 UTC [1235] LOG:  connection authorized: user=app database=shop\n",
+			want: []string{"SELECT * FROM users WHERE id = 1", "UPDATE users\n\tSET name = 'x'\n\tWHERE id = 2", "SELECT * FROM t WHERE id = $1"},
+		},
+		{
+			name: "timestamped lines without semicolons",
+			text: "[2024-05-01 10:00:00] SELECT 1\n[2024-05-01 10:00:01] SELECT 2\n",
+			want: []string{"SELECT 1", "SELECT 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, match := range (LogExtractor{}).Extract(tt.text) {
+				got = append(got, match.Text)
+				if !strings.HasPrefix(tt.text[match.Offset:], match.Text) {
+					t.Errorf("match %q at offset %d, want the offset of its text", match.Text, match.Offset)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LogExtractor.Extract(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
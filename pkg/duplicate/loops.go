@@ -0,0 +1,150 @@
+package duplicate
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The regular expressions used to recognize loops, compiled once.
+var (
+	// loopHeadRe matches the line opening a loop body in PHP, Go and the
+	// other languages with braces.
+	loopHeadRe = regexp.MustCompile(`^(?:for|foreach|while)\b|^do$`)
+	// pyLoopRe and pyScopeRe match the Python lines that open a loop, and a
+	// function or class, whose body the loop can't be seen beyond.
+	pyLoopRe  = regexp.MustCompile(`^(?:async\s+)?(?:for|while)\b`)
+	pyScopeRe = regexp.MustCompile(`^(?:async\s+)?(?:def|class)\b`)
+)
+
+// inLoops reports, for each of matches, whether the query sits in the body
+// of a loop, which suggests it runs once per iteration: the N+1 query
+// pattern. Python blocks are found by indentation, other files by their
+// braces. Braces in strings and comments aren't told apart, so this is a
+// heuristic. matches must be sorted by offset, so text is only scanned once.
+func inLoops(path, text string, matches []Match) []bool {
+	if strings.HasSuffix(strings.ToLower(path), ".py") {
+		return inPythonLoops(text, matches)
+	}
+
+	// blocks holds whether each enclosing block is a loop body
+	var blocks []bool
+	loops := 0
+	result := make([]bool, len(matches))
+	pos, lineStart, prevLineStart := 0, 0, 0
+	for m, match := range matches {
+		for ; pos < match.Offset; pos++ {
+			switch text[pos] {
+			case '\n':
+				prevLineStart, lineStart = lineStart, pos+1
+			case '{':
+				// Check the line heading the block, the line before when
+				// the brace is on a line of its own
+				head := strings.TrimSpace(text[lineStart:pos])
+				if head == "" && lineStart > 0 {
+					head = strings.TrimSpace(text[prevLineStart : lineStart-1])
+				}
+				loop := loopHeadRe.MatchString(head)
+				blocks = append(blocks, loop)
+				if loop {
+					loops++
+				}
+			case '}':
+				if len(blocks) > 0 {
+					if blocks[len(blocks)-1] {
+						loops--
+					}
+					blocks = blocks[:len(blocks)-1]
+				}
+			}
+		}
+		result[m] = loops > 0
+	}
+	return result
+}
+
+// pyBlock is an earlier line of a Python file that indents the lines after
+// it, see inPythonLoops.
+type pyBlock struct {
+	indent int
+	loop   bool
+	scope  bool
+}
+
+// inPythonLoops is inLoops for Python, where the enclosing blocks of a line
+// are the earlier lines indented less than it. The innermost loop, function
+// or class among them decides.
+func inPythonLoops(text string, matches []Match) []bool {
+	// blocks holds the lines that can still enclose later ones, from the
+	// least indented to the most
+	var blocks []pyBlock
+	result := make([]bool, len(matches))
+	m := 0
+	for lineStart := 0; lineStart <= len(text) && m < len(matches); {
+		lineEnd := strings.IndexByte(text[lineStart:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(text)
+		} else {
+			lineEnd += lineStart
+		}
+		line := text[lineStart:lineEnd]
+		indent := indentation(line)
+
+		for ; m < len(matches) && matches[m].Offset <= lineEnd; m++ {
+			result[m] = pyEnclosingLoop(blocks, indent)
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			for len(blocks) > 0 && blocks[len(blocks)-1].indent >= indent {
+				blocks = blocks[:len(blocks)-1]
+			}
+			blocks = append(blocks, pyBlock{
+				indent: indent,
+				loop:   pyLoopRe.MatchString(trimmed),
+				scope:  pyScopeRe.MatchString(trimmed),
+			})
+		}
+		lineStart = lineEnd + 1
+	}
+	return result
+}
+
+// pyEnclosingLoop reports whether the innermost loop, function or class of
+// blocks enclosing a line indented by indent is a loop.
+func pyEnclosingLoop(blocks []pyBlock, indent int) bool {
+	for i := len(blocks) - 1; i >= 0; i-- {
+		switch block := blocks[i]; {
+		case block.indent >= indent:
+		case block.loop:
+			return true
+		case block.scope:
+			return false
+		}
+	}
+	return false
+}
+
+// indentation returns the number of leading spaces and tabs of line.
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// LoopQueries returns, per normalized query, the occurrences found inside
+// loops, keeping the queries with at least threshold of them. Unlike
+// duplicates, a single occurrence counts: a query in a loop runs many times.
+// The groups must come from a scan with Config.DetectLoops set.
+func LoopQueries(groups map[string][]QueryResult, threshold int) map[string][]QueryResult {
+	loops := make(map[string][]QueryResult)
+	for key, results := range groups {
+		var inLoops []QueryResult
+		for _, result := range results {
+			if result.InLoop {
+				inLoops = append(inLoops, result)
+			}
+		}
+		if len(inLoops) > 0 && len(inLoops) >= threshold {
+			loops[key] = inLoops
+		}
+	}
+	return loops
+}
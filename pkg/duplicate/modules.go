@@ -0,0 +1,71 @@
+package duplicate
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Modules returns the distinct modules results come from, sorted. The module
+// of a file is the top-level directory below root it is in, or "." for the
+// files directly in root. An empty root means the paths are already relative
+// to it.
+func Modules(results []QueryResult, root string) []string {
+	seen := make(map[string]bool)
+	var modules []string
+	for _, result := range results {
+		if module := moduleOf(root, result.FilePath); !seen[module] {
+			seen[module] = true
+			modules = append(modules, module)
+		}
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// KeepCrossModule removes the groups of duplicates found in a single module,
+// see Modules, and returns how many were removed. Queries duplicated across
+// modules often point to shared code that is missing.
+func KeepCrossModule(duplicates map[string][]QueryResult, root string) int {
+	removed := 0
+	for key, results := range duplicates {
+		if len(Modules(results, root)) < 2 {
+			delete(duplicates, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// moduleOf returns the module of the file at path. Files outside root
+// keep the ../ in front of their module, so they aren't lumped together.
+func moduleOf(root, path string) string {
+	rel := path
+	if root != "" {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return "."
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return "."
+		}
+		rel, err = filepath.Rel(absRoot, absPath)
+		if err != nil {
+			return "."
+		}
+	}
+	parts := strings.Split(strings.TrimPrefix(filepath.ToSlash(filepath.Clean(rel)), "/"), "/")
+	up := 0
+	for up < len(parts)-1 && parts[up] == ".." {
+		up++
+	}
+	if up == len(parts)-1 {
+		// A file directly in root, or in a directory above it
+		up--
+	}
+	if up < 0 {
+		return "."
+	}
+	return strings.Join(parts[:up+1], "/")
+}
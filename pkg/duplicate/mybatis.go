@@ -0,0 +1,204 @@
+package duplicate
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The regular expressions used by FindMyBatisQueries, compiled once.
+var (
+	// mybatisStatementRe matches the opening tag of a mapped statement,
+	// with the element name in the first submatch.
+	mybatisStatementRe = regexp.MustCompile(`<(select|insert|update|delete)\b[^>]*>`)
+	// mybatisFragmentRe matches a <sql> element, with its id in the first
+	// submatch and its content in the second.
+	mybatisFragmentRe = regexp.MustCompile(`(?s)<sql\b[^>]*?\bid\s*=\s*"([^"]*)"[^>]*>(.*?)</sql>`)
+	// mybatisNamespaceRe matches the namespace of a mapper.
+	mybatisNamespaceRe = regexp.MustCompile(`<mapper\b[^>]*?\bnamespace\s*=\s*"([^"]*)"`)
+	// mybatisAttrRe matches an attribute of a tag.
+	mybatisAttrRe = regexp.MustCompile(`([\w:]+)\s*=\s*"([^"]*)"`)
+	// mybatisParamRe matches #{param} and ${param} parameters.
+	mybatisParamRe = regexp.MustCompile(`[#$]\{[^}]*\}`)
+	// mybatisWhereRe and mybatisCommaRe match what the <where>, <set> and
+	// <trim> elements strip: a leading AND or OR, and a trailing comma.
+	mybatisWhereRe = regexp.MustCompile(`(?i)\b(where)\s+(?:and|or)\b`)
+	mybatisCommaRe = regexp.MustCompile(`,(\s*)(?i:(where)\b|$)`)
+)
+
+// maxIncludeDepth bounds how deeply <include> elements are inlined, so that
+// fragments including each other end.
+const maxIncludeDepth = 8
+
+// xmlEntities are the predefined XML entities.
+var xmlEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&", "&quot;", `"`, "&apos;", "'")
+
+// FindMyBatisQueries returns the SQL of the <select>, <insert>, <update> and
+// <delete> elements of a MyBatis mapper. Dynamic SQL elements such as <if>
+// and <foreach> are replaced by their content, so every optional condition
+// is included, and #{param} and ${param} become ? placeholders. An <include>
+// is replaced by the <sql> fragment it refers to, or by its refid when the
+// fragment isn't defined in the same mapper.
+func FindMyBatisQueries(text string) []Match {
+	fragments := mybatisFragments(text)
+	var result []Match
+	for _, loc := range mybatisStatementRe.FindAllStringSubmatchIndex(text, -1) {
+		if strings.HasSuffix(text[loc[0]:loc[1]], "/>") {
+			continue
+		}
+		name := text[loc[2]:loc[3]]
+		end := strings.Index(text[loc[1]:], "</"+name)
+		if end < 0 {
+			continue
+		}
+
+		body := text[loc[1] : loc[1]+end]
+		query := strings.TrimSpace(mybatisSQL(body, fragments))
+		if query == "" || !statementStartRe.MatchString(query[:min(len(query), maxKeywordLength)]) {
+			continue
+		}
+		offset := loc[1] + len(body) - len(strings.TrimLeft(body, " \t\r\n"))
+		result = append(result, Match{Text: query, Offset: offset})
+	}
+	return result
+}
+
+// mybatisFragments returns the content of the <sql> fragments of a mapper,
+// keyed by their id, both plain and qualified with the namespace.
+func mybatisFragments(text string) map[string]string {
+	var namespace string
+	if m := mybatisNamespaceRe.FindStringSubmatch(text); m != nil {
+		namespace = m[1]
+	}
+
+	fragments := make(map[string]string)
+	for _, m := range mybatisFragmentRe.FindAllStringSubmatch(text, -1) {
+		fragments[m[1]] = m[2]
+		if namespace != "" {
+			fragments[namespace+"."+m[1]] = m[2]
+		}
+	}
+	return fragments
+}
+
+// mybatisSQL turns the body of a mapped statement into SQL: comments are
+// dropped, CDATA sections kept as is, entities decoded, fragments included
+// and the tags of dynamic SQL replaced by the prefix and suffix they add, if
+// any.
+func mybatisSQL(body string, fragments map[string]string) string {
+	query := mybatisParamRe.ReplaceAllString(mybatisText(body, fragments, 0), "?")
+	query = mybatisWhereRe.ReplaceAllString(query, "$1")
+	return mybatisCommaRe.ReplaceAllString(query, "$1$2")
+}
+
+// mybatisText is mybatisSQL without the rewriting of parameters and of what
+// <where>, <set> and <trim> strip, applied once to the whole statement.
+// depth is the number of <include> elements being inlined.
+func mybatisText(body string, fragments map[string]string, depth int) string {
+	var b strings.Builder
+	var closers []string
+	for i := 0; i < len(body); {
+		switch {
+		case strings.HasPrefix(body[i:], "<![CDATA["):
+			start := i + len("<![CDATA[")
+			end := strings.Index(body[start:], "]]>")
+			if end < 0 {
+				end = len(body) - start
+			}
+			b.WriteString(body[start : start+end])
+			i = min(start+end+len("]]>"), len(body))
+		case strings.HasPrefix(body[i:], "<!--"):
+			end := strings.Index(body[i:], "-->")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + len("-->")
+		case body[i] == '<':
+			end := strings.IndexByte(body[i:], '>')
+			if end < 0 {
+				end = len(body) - i - 1
+			}
+			tag := body[i : i+end+1]
+			i += end + 1
+			b.WriteString(" ")
+
+			switch {
+			case strings.HasPrefix(tag, "</"):
+				if len(closers) > 0 {
+					b.WriteString(closers[len(closers)-1])
+					closers = closers[:len(closers)-1]
+				}
+			case mybatisTagName(tag) == "include":
+				b.WriteString(mybatisInclude(tag, fragments, depth))
+				if !strings.HasSuffix(tag, "/>") {
+					closers = append(closers, "")
+				}
+			case strings.HasSuffix(tag, "/>"):
+				// <bind/> and the like have nothing to add
+			default:
+				open, close := mybatisAffixes(tag)
+				b.WriteString(open)
+				closers = append(closers, close)
+			}
+			b.WriteString(" ")
+		default:
+			end := strings.IndexByte(body[i:], '<')
+			if end < 0 {
+				end = len(body) - i
+			}
+			b.WriteString(xmlEntities.Replace(body[i : i+end]))
+			i += end
+		}
+	}
+
+	return b.String()
+}
+
+// mybatisInclude returns the SQL of the fragment an <include> tag refers to,
+// or its refid if there is no such fragment, so that statements including
+// different fragments stay apart.
+func mybatisInclude(tag string, fragments map[string]string, depth int) string {
+	var refid string
+	for _, attr := range mybatisAttrRe.FindAllStringSubmatch(tag, -1) {
+		if attr[1] == "refid" {
+			refid = attr[2]
+		}
+	}
+	fragment, ok := fragments[refid]
+	if !ok || depth >= maxIncludeDepth {
+		return refid
+	}
+	return mybatisText(fragment, fragments, depth+1)
+}
+
+// mybatisTagName returns the element name of an opening, closing or self
+// closing tag.
+func mybatisTagName(tag string) string {
+	fields := strings.Fields(strings.Trim(tag, "<>/ "))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// mybatisAffixes returns the text a dynamic SQL element adds before and
+// after its content: WHERE and SET for <where> and <set>, and the open and
+// close, or prefix and suffix, attributes of <foreach> and <trim>.
+func mybatisAffixes(tag string) (string, string) {
+	switch mybatisTagName(tag) {
+	case "where":
+		return "WHERE", ""
+	case "set":
+		return "SET", ""
+	}
+
+	var open, close string
+	for _, attr := range mybatisAttrRe.FindAllStringSubmatch(tag, -1) {
+		switch attr[1] {
+		case "open", "prefix":
+			open = xmlEntities.Replace(attr[2])
+		case "close", "suffix":
+			close = xmlEntities.Replace(attr[2])
+		}
+	}
+	return open, close
+}
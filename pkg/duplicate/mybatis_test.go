@@ -0,0 +1,116 @@
+package duplicate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindMyBatisQueries(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string // normalized
+	}{
+		{
+			name: "parameters",
+			text: `<select id="a">SELECT * FROM t WHERE a = #{a} AND b = ${b}</select>`,
+			want: []string{"select * from t where a = ? and b = ?"},
+		},
+		{
+			name: "include",
+			text: `<sql id="cols">id, name</sql><select id="a">SELECT <include refid="cols"/> FROM t</select>`,
+			want: []string{"select id, name from t"},
+		},
+		{
+			name: "include qualified with the namespace",
+			text: `<mapper namespace="com.example.M"><sql id="cols">id, name</sql>` +
+				`<select id="a">SELECT <include refid="com.example.M.cols"/> FROM t</select></mapper>`,
+			want: []string{"select id, name from t"},
+		},
+		{
+			name: "include with properties",
+			text: `<sql id="cols">id, ${alias}.name</sql>` +
+				`<select id="a">SELECT <include refid="cols"><property name="alias" value="u"/></include> FROM t</select>`,
+			want: []string{"select id, ?.name from t"},
+		},
+		{
+			name: "nested includes",
+			text: `<sql id="from">FROM t</sql><sql id="base">SELECT * <include refid="from"/></sql>` +
+				`<select id="a"><include refid="base"/> WHERE id = #{id}</select>`,
+			want: []string{"select * from t where id = ?"},
+		},
+		{
+			name: "include of an unknown fragment",
+			text: `<select id="a">SELECT <include refid="other.cols"/> FROM t</select>`,
+			want: []string{"select other.cols from t"},
+		},
+		{
+			name: "include cycle",
+			text: `<sql id="a">SELECT <include refid="b"/></sql><sql id="b"><include refid="a"/></sql>` +
+				`<select id="s"><include refid="a"/> FROM t</select>`,
+			want: []string{"select select select select a from t"},
+		},
+		{
+			name: "where strips a leading AND",
+			text: `<select id="a">SELECT * FROM t <where><if test="x">AND a = #{a}</if></where></select>`,
+			want: []string{"select * from t where a = ?"},
+		},
+		{
+			name: "set strips a trailing comma",
+			text: `<update id="a">UPDATE t <set><if test="x">a = #{a},</if></set> WHERE id = #{id}</update>`,
+			want: []string{"update t set a = ? where id = ?"},
+		},
+		{
+			name: "foreach",
+			text: `<select id="a">SELECT * FROM t WHERE id IN <foreach collection="ids" open="(" separator="," close=")">#{id}</foreach></select>`,
+			want: []string{"select * from t where id in ( ... ) "},
+		},
+		{
+			name: "entities, CDATA and comments",
+			text: `<select id="a"><!-- a comment -->SELECT * FROM t WHERE a &lt; #{a}<![CDATA[ AND b > 1 ]]></select>`,
+			want: []string{"select * from t where a < ? and b > N"},
+		},
+		{
+			name: "empty and non SQL statements",
+			text: `<select id="a"/><select id="b">  </select><select id="c">not sql</select>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, match := range FindMyBatisQueries(tt.text) {
+				got = append(got, Normalize(match.Text))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindMyBatisQueries(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindMyBatisQueriesFixture(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "test-mybatis.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+
+	matches := FindMyBatisQueries(text)
+	if len(matches) != 6 {
+		t.Fatalf("FindMyBatisQueries found %d statements, want 6", len(matches))
+	}
+	want := "select id, customer_id, status, total from orders where id = ?"
+	for _, match := range matches[:2] {
+		if got := Normalize(match.Text); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", match.Text, got, want)
+		}
+	}
+	for _, match := range matches {
+		if line := lineBreaks(text, 0, match.Offset) + 1; text[match.Offset] == '\n' || line < 6 {
+			t.Errorf("match %q at offset %d, line %d, doesn't start in a statement", match.Text, match.Offset, line)
+		}
+	}
+}
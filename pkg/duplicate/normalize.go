@@ -0,0 +1,364 @@
+package duplicate
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Quoted string literals. A quote inside the literal may be escaped either
+// by doubling it or with a backslash.
+const (
+	singleQuotedPattern = `'(?:[^'\\]|\\.|'')*'`
+	doubleQuotedPattern = `"(?:[^"\\]|\\.|"")*"`
+)
+
+// numberPattern matches number literals, exponents included, but not the
+// digits in names such as col2 or x1e5.
+const numberPattern = `\b\d+(?:\.\d+)?(?:[eE][+-]?\d+)?\b`
+
+// The regular expressions used by NormalizeQuery, compiled once.
+var (
+	literalRe            = regexp.MustCompile(singleQuotedPattern + "|" + doubleQuotedPattern)
+	backtickIdentifierRe = regexp.MustCompile("`([^`]*)`")
+	quotedIdentifierRe   = regexp.MustCompile("`([^`]*)`|\"([^\"]*)\"")
+	whitespaceRe         = regexp.MustCompile(`[\s\n\r\t]+`)
+	booleanRe            = regexp.MustCompile(`= (?:true|false)\b`)
+	// tableSuffixRe matches the table names after FROM, JOIN, INTO and
+	// UPDATE ending in numeric suffixes, such as orders_2024 or
+	// logs_2024_01, capturing the name without them.
+	tableSuffixRe = regexp.MustCompile(`\b(from|join|into|update) ((?:\w+\.)?[a-z_]\w*?)(?:_\d+)+\b`)
+	// valueRe matches the N and S literal tokens and the placeholders of
+	// prepared statements: ?, :name, $1 (already $N) and @name. A :name
+	// after another colon is part of a :: cast and is left alone.
+	valueRe = regexp.MustCompile(`(^|[^:\w])(?:\?|:[a-z_]\w*|\$N\b|@[a-z_]\w*|\bN\b|\bS\b)`)
+	// inListRe matches IN lists of literals or placeholders.
+	inListRe = regexp.MustCompile(`\bin \( [NS?](?:, [NS?])* \)`)
+	// tokenRe matches the N and S literal tokens, the only uppercase words
+	// of a normalized query.
+	tokenRe = regexp.MustCompile(`\b[NS]\b`)
+)
+
+// replacements are the built-in normalization steps, applied in order to the
+// lowercased query. Their names are shown by ExplainQuery.
+var replacements = []struct {
+	name        string
+	re          *regexp.Regexp
+	replacement string
+}{
+	{"inequality", regexp.MustCompile(`<>`), "!="},                             // Both inequality operators to !=
+	{"comparison spacing", regexp.MustCompile(`\s*([!<>]?=)\s*`), " $1 "},      // Normalize spaces around comparisons
+	{"comma spacing", regexp.MustCompile(`\s*,\s*`), ", "},                     // Normalize spaces around commas
+	{"spaces", regexp.MustCompile(`\s+`), " "},                                 // Any remaining multiple spaces to single
+	{"numbers", regexp.MustCompile(numberPattern), "N"},                        // Numbers, exponents included, to N, leaving digits in names such as col2 alone
+	{"limit offset", regexp.MustCompile(`\blimit N, N\b`), "limit N offset N"}, // MySQL LIMIT offset, count form
+	{"single quoted strings", regexp.MustCompile(singleQuotedPattern), "S"},    // Quoted strings to S
+	{"double quoted strings", regexp.MustCompile(doubleQuotedPattern), "S"},    // Double quoted strings to S
+	{"string spacing", regexp.MustCompile(`(\w)S`), "$1 S"},                    // Separate S from words a literal touched, as in THEN'a'ELSE
+	{"string spacing", regexp.MustCompile(`S(\w)`), "S $1"},                    // S is the only uppercase S left, so this can't split a word
+	{"case without else", regexp.MustCompile(`\belse null end\b`), "end"},      // ELSE NULL is what CASE defaults to
+	{"opening parentheses", regexp.MustCompile(`\s*\(\s*`), " ( "},             // Normalize spaces around parentheses, so count(*) matches COUNT ( * )
+	{"closing parentheses", regexp.MustCompile(`\s*\)\s*`), " ) "},
+	{"in lists", inListRe, "in ( ... )"}, // IN lists of any length
+}
+
+// The SQL dialects NormalizeOptions.Dialect can select. They differ in what
+// double quotes mean: a string literal in MySQL, an identifier in PostgreSQL
+// and standard SQL.
+const (
+	DialectMySQL    = "mysql"
+	DialectPostgres = "postgres"
+	DialectANSI     = "ansi"
+)
+
+// NormalizeOptions tweaks how queries are normalized.
+type NormalizeOptions struct {
+	// KeepStringLiterals preserves the (lowercased) contents of string
+	// literals instead of collapsing them to S.
+	KeepStringLiterals bool `json:"keep-string-literals"`
+	// NormalizeAliases rewrites table and column aliases to positional
+	// placeholders.
+	NormalizeAliases bool `json:"normalize-aliases"`
+	// SortColumns sorts comma separated column lists so queries that only
+	// differ in column order compare equal. See sortColumns for its limits.
+	SortColumns bool `json:"sort-columns"`
+	// NormalizeOrderBy drops the explicit ASC of ORDER BY keys, and
+	// SortOrderBy also sorts the keys, so ORDER BY lists only differing in
+	// key order compare equal. See normalizeOrderBy for their limits.
+	NormalizeOrderBy bool `json:"normalize-orderby"`
+	SortOrderBy      bool `json:"sort-orderby"`
+	// NormalizeBooleans compares TRUE and FALSE like the numbers 1 and 0
+	// when they are the right hand side of = or !=, as in dialects without
+	// a boolean type.
+	NormalizeBooleans bool `json:"normalize-booleans"`
+	// NormalizeTableSuffixes replaces the numeric suffixes of the tables
+	// queried, as in orders_2023 and orders_2024, with _N so queries of
+	// per-tenant, per-period or sharded tables compare equal.
+	NormalizeTableSuffixes bool `json:"normalize-table-suffixes"`
+	// MergePlaceholders turns literals and prepared statement placeholders
+	// such as ?, :id, $1 and @p1 into the same ? token, so a query
+	// compares equal whether its values are inlined or bound.
+	MergePlaceholders bool `json:"merge-placeholders"`
+	// Dialect is one of the Dialect constants, DialectMySQL if empty.
+	Dialect string `json:"dialect"`
+	// NumToken and StrToken replace number and string literals, N and S
+	// if empty. Custom tokens avoid clashes with real identifiers when the
+	// normalized queries are parsed further. They should differ from each
+	// other and from lowercase identifiers, or literals are confused with
+	// each other or with column names.
+	NumToken string `json:"num-token"`
+	StrToken string `json:"str-token"`
+	// Rules are custom replacements, see LoadRules. They run on the
+	// lowercased query after the built-in replacements, or instead of them
+	// when ReplaceBuiltinRules is set.
+	Rules               []Rule `json:"-"`
+	ReplaceBuiltinRules bool   `json:"-"`
+}
+
+// Normalize returns the normalized form of query with the default options,
+// the form the command line tool groups queries by unless told otherwise.
+func Normalize(query string) string {
+	return NormalizeQuery(query, NormalizeOptions{})
+}
+
+// NormalizeQuery reduces a query to a canonical form so that queries that
+// only differ in whitespace, case or literal values compare equal.
+func NormalizeQuery(query string, opts NormalizeOptions) string {
+	return normalize(query, opts, nil)
+}
+
+// normalize is NormalizeQuery, calling step, when not nil, with the name and
+// result of every stage.
+func normalize(query string, opts NormalizeOptions, step func(name, result string)) string {
+	if step == nil {
+		step = func(string, string) {}
+	}
+
+	// Dollar-quoted bodies may contain anything, including comment markers,
+	// so they are set aside first
+	normalized, dollarLiterals := replaceDollarQuotes(query, "\x01")
+	step("dollar quotes set aside", normalized)
+	normalized = stripComments(normalized)
+	step("comments", normalized)
+
+	// Quoted identifiers compare equal to unquoted ones
+	if opts.Dialect == DialectPostgres || opts.Dialect == DialectANSI {
+		normalized = quotedIdentifierRe.ReplaceAllString(normalized, "$1$2")
+	} else {
+		normalized = backtickIdentifierRe.ReplaceAllString(normalized, "$1")
+	}
+	step("quoted identifiers", normalized)
+
+	// Set string literals aside so the rules below leave their contents alone
+	var literals []string
+	if opts.KeepStringLiterals {
+		literals = literalRe.FindAllString(normalized, -1)
+		normalized = literalRe.ReplaceAllString(normalized, "\x00")
+		step("string literals set aside", normalized)
+	}
+
+	// Collapse all whitespace variants into single spaces
+	normalized = whitespaceRe.ReplaceAllString(normalized, " ")
+	step("whitespace", normalized)
+	normalized = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(normalized), ";"))
+	step("trailing semicolon", normalized)
+	normalized = strings.ToLower(normalized)
+	step("lowercase", normalized)
+	if !opts.KeepStringLiterals {
+		normalized = strings.ReplaceAll(normalized, "\x01", "S")
+		step("dollar quoted strings", normalized)
+	}
+
+	if !opts.ReplaceBuiltinRules {
+		for _, r := range replacements {
+			normalized = r.re.ReplaceAllString(normalized, r.replacement)
+			step(r.name, normalized)
+		}
+		normalized = collapseValuesRows(normalized)
+		step("values rows", normalized)
+	}
+	for _, rule := range opts.Rules {
+		normalized = rule.Pattern.ReplaceAllString(normalized, rule.Replacement)
+		step("rule "+rule.Pattern.String(), normalized)
+	}
+
+	if opts.NormalizeBooleans {
+		normalized = booleanRe.ReplaceAllString(normalized, "= N")
+		step("booleans", normalized)
+	}
+
+	if opts.NormalizeTableSuffixes {
+		normalized = tableSuffixRe.ReplaceAllString(normalized, "$1 ${2}_N")
+		step("table suffixes", normalized)
+	}
+
+	if opts.MergePlaceholders {
+		normalized = valueRe.ReplaceAllString(normalized, "${1}?")
+		normalized = inListRe.ReplaceAllString(normalized, "in ( ... )")
+		step("placeholders", normalized)
+	}
+
+	if opts.NormalizeAliases {
+		normalized = normalizeAliases(normalized)
+		step("aliases", normalized)
+	}
+
+	if opts.NumToken != "" && opts.NumToken != "N" || opts.StrToken != "" && opts.StrToken != "S" {
+		normalized = replaceTokens(normalized, opts)
+		step("tokens", normalized)
+	}
+
+	for _, literal := range literals {
+		normalized = strings.Replace(normalized, "\x00", strings.ToLower(literal), 1)
+	}
+	for _, literal := range dollarLiterals {
+		normalized = strings.Replace(normalized, "\x01", strings.ToLower(literal), 1)
+	}
+	step("literals restored", normalized)
+
+	// Sorted last, as sorting would move the literal placeholders around
+	if opts.NormalizeOrderBy || opts.SortOrderBy {
+		normalized = normalizeOrderBy(normalized, opts.SortOrderBy)
+		step("order by", normalized)
+	}
+	if opts.SortColumns {
+		normalized = sortColumns(normalized)
+		step("column order", normalized)
+	}
+
+	return normalized
+}
+
+// replaceTokens swaps the N and S tokens of a normalized query for the
+// tokens opts selects.
+func replaceTokens(query string, opts NormalizeOptions) string {
+	return tokenRe.ReplaceAllStringFunc(query, func(token string) string {
+		switch {
+		case token == "N" && opts.NumToken != "":
+			return opts.NumToken
+		case token == "S" && opts.StrToken != "":
+			return opts.StrToken
+		}
+		return token
+	})
+}
+
+// collapseValuesRows reduces the rows of a multi-row VALUES list to one when
+// they are all alike after normalization, so bulk inserts of any size
+// compare equal. Rows are compared as a whole, nested parentheses included,
+// and lists mixing different rows are left alone.
+func collapseValuesRows(query string) string {
+	const keyword = "values ("
+	var b strings.Builder
+	for {
+		i := strings.Index(query, keyword)
+		if i < 0 {
+			b.WriteString(query)
+			return b.String()
+		}
+		rowStart := i + len(keyword) - 1
+		rowEnd := closingParen(query, rowStart)
+		if rowEnd < 0 {
+			b.WriteString(query)
+			return b.String()
+		}
+		row := query[rowStart : rowEnd+1]
+
+		// Find where the run of identical rows ends
+		end := rowEnd + 1
+		for {
+			next := strings.TrimLeft(query[end:], " ")
+			if !strings.HasPrefix(next, ",") {
+				break
+			}
+			next = strings.TrimLeft(next[1:], " ")
+			if !strings.HasPrefix(next, row) {
+				break
+			}
+			end = len(query) - len(next) + len(row)
+		}
+
+		// Only collapse when no different row follows
+		rest := strings.TrimLeft(query[end:], " ")
+		if strings.HasPrefix(rest, ",") && strings.HasPrefix(strings.TrimLeft(rest[1:], " "), "(") {
+			end = len(query) - len(rest)
+			b.WriteString(query[:end])
+		} else {
+			b.WriteString(query[:rowEnd+1])
+		}
+		query = query[end:]
+	}
+}
+
+// closingParen returns the position of the parenthesis closing the one at
+// pos, or -1.
+func closingParen(s string, pos int) int {
+	depth := 0
+	for i := pos; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// stripComments removes -- line comments and /* */ block comments from a
+// query. Block comments may nest, so nested-looking comments are removed as a
+// whole, and an unterminated one runs to the end of the query. Comment markers
+// inside quoted literals are left alone.
+func stripComments(query string) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		if quote != 0 {
+			b.WriteByte(c)
+			if c == '\\' && i+1 < len(query) {
+				i++
+				b.WriteByte(query[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			b.WriteByte(c)
+		case strings.HasPrefix(query[i:], "--"):
+			// Drop everything up to, but not including, the newline
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return b.String()
+			}
+			i += end - 1
+		case strings.HasPrefix(query[i:], "/*"):
+			depth := 0
+			for i < len(query) {
+				if strings.HasPrefix(query[i:], "/*") {
+					depth++
+					i += 2
+				} else if strings.HasPrefix(query[i:], "*/") {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					i++
+				}
+			}
+			i--
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
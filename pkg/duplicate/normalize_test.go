@@ -0,0 +1,368 @@
+package duplicate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "whitespace",
+			query: "SELECT  *\n\tFROM   users\n WHERE id = 1",
+			want:  "select * from users where id = N",
+		},
+		{
+			name:  "case",
+			query: "select * FROM Users where ID = 1",
+			want:  "select * from users where id = N",
+		},
+		{
+			name:  "trailing semicolon",
+			query: "SELECT * FROM t;",
+			want:  "select * from t",
+		},
+		{
+			name:  "integers and decimals",
+			query: "SELECT * FROM t WHERE a = 42 AND b = 3.14",
+			want:  "select * from t where a = N and b = N",
+		},
+		{
+			name:  "exponents",
+			query: "SELECT * FROM t WHERE a = 1.5e10 AND b = 1e5 AND c = 2E-3 AND d = 3.0e+2",
+			want:  "select * from t where a = N and b = N and c = N and d = N",
+		},
+		{
+			name:  "digits in names",
+			query: "SELECT col2 FROM t2 WHERE x1e5 = 1",
+			want:  "select col2 from t2 where x1e5 = N",
+		},
+		{
+			name:  "single and double quoted strings",
+			query: `SELECT * FROM t WHERE name = 'alice' AND city = "Paris"`,
+			want:  "select * from t where name = S and city = S",
+		},
+		{
+			name:  "escaped quotes",
+			query: `SELECT * FROM t WHERE name = 'O''Brien' AND note = 'say \'hi\''`,
+			want:  "select * from t where name = S and note = S",
+		},
+		{
+			name:  "commas and parentheses in strings",
+			query: `SELECT * FROM t WHERE a = 'a, (b)' AND b = 'it''s, )' AND c = "x\"y"`,
+			want:  "select * from t where a = S and b = S and c = S",
+		},
+		{
+			name:  "commas and parentheses in listed strings",
+			query: `SELECT * FROM t WHERE a IN ('a, (b)', 'it''s, )', "x\"y")`,
+			want:  "select * from t where a in ( ... ) ",
+		},
+		{
+			name:  "commas and parentheses in values rows",
+			query: `INSERT INTO t (a, b) VALUES ('a, (b)', "x\"y"), ('it''s, )', 'c')`,
+			want:  "insert into t ( a, b ) values ( S, S ) ",
+		},
+		{
+			name:  "strings touching keywords",
+			query: "SELECT CASE WHEN a = 2 THEN'y'END FROM t",
+			want:  "select case when a = N then S end from t",
+		},
+		{
+			name:  "commas",
+			query: "SELECT a,b ,  c FROM t",
+			want:  "select a, b, c from t",
+		},
+		{
+			name:  "parentheses",
+			query: "SELECT COUNT( * ) FROM t WHERE (a = 1)",
+			want:  "select count ( * ) from t where ( a = N ) ",
+		},
+		{
+			name:  "aggregates",
+			query: "SELECT COUNT(*), SUM(x) FROM t",
+			want:  "select count ( * ) , sum ( x ) from t",
+		},
+		{
+			name:  "aggregates with spaces",
+			query: "select count( * ),sum (x) from t",
+			want:  "select count ( * ) , sum ( x ) from t",
+		},
+		{
+			name:  "user functions",
+			query: "SELECT order_total(id), COUNT (*) FROM t WHERE id IN (1, 2)",
+			want:  "select order_total ( id ) , count ( * ) from t where id in ( ... ) ",
+		},
+		{
+			name:  "user functions with spaces",
+			query: "SELECT order_total ( id ), count(*) FROM t WHERE id IN(3)",
+			want:  "select order_total ( id ) , count ( * ) from t where id in ( ... ) ",
+		},
+		{
+			name:  "inequality",
+			query: "SELECT * FROM t WHERE a <> 1 AND b!=2",
+			want:  "select * from t where a != N and b != N",
+		},
+		{
+			name:  "in list of literals",
+			query: "SELECT * FROM t WHERE id IN (1, 2, 3)",
+			want:  "select * from t where id in ( ... ) ",
+		},
+		{
+			name:  "in list of placeholders",
+			query: "SELECT * FROM t WHERE id IN (?, ?)",
+			want:  "select * from t where id in ( ... ) ",
+		},
+		{
+			name:  "mysql limit offset",
+			query: "SELECT * FROM t LIMIT 10, 20",
+			want:  "select * from t limit N offset N",
+		},
+		{
+			name:  "limit offset",
+			query: "SELECT * FROM t LIMIT 20 OFFSET 10",
+			want:  "select * from t limit N offset N",
+		},
+		{
+			name:  "values rows",
+			query: "INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y'), (3, 'z')",
+			want:  "insert into t ( a, b ) values ( N, S ) ",
+		},
+		{
+			name:  "case else null",
+			query: "SELECT CASE WHEN a = 1 THEN 'x' ELSE NULL END FROM t",
+			want:  "select case when a = N then S end from t",
+		},
+		{
+			name:  "dollar quotes",
+			query: "SELECT $$it's$$, $tag$a;b$tag$ FROM t",
+			want:  "select S, S from t",
+		},
+		{
+			name:  "comments",
+			query: "SELECT * FROM t -- trailing\nWHERE a = 1 /* block */",
+			want:  "select * from t where a = N",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.query); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeQueryOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		opts  NormalizeOptions
+		want  string
+	}{
+		{
+			name:  "keep string literals",
+			query: "SELECT * FROM t WHERE status = 'Active'",
+			opts:  NormalizeOptions{KeepStringLiterals: true},
+			want:  "select * from t where status = 'active'",
+		},
+		{
+			name:  "merge placeholders",
+			query: "SELECT * FROM t WHERE a = :a AND b = $1 AND c = 3",
+			opts:  NormalizeOptions{MergePlaceholders: true},
+			want:  "select * from t where a = ? and b = ? and c = ?",
+		},
+		{
+			name:  "custom tokens",
+			query: "SELECT 'a', 1 FROM t",
+			opts:  NormalizeOptions{NumToken: "{num}", StrToken: "{str}"},
+			want:  "select {str}, {num} from t",
+		},
+		{
+			name:  "sort insert columns",
+			query: "INSERT INTO users (name, email, id) VALUES (?, ?, ?)",
+			opts:  NormalizeOptions{SortColumns: true},
+			want:  "insert into users ( email, id, name ) values ( ?, ?, ? ) ",
+		},
+		{
+			name:  "sort select projection",
+			query: "SELECT name, email, id FROM users WHERE id = 1",
+			opts:  NormalizeOptions{SortColumns: true},
+			want:  "select email, id, name from users where id = N",
+		},
+		{
+			name:  "sort distinct projection",
+			query: "SELECT DISTINCT b, a FROM t",
+			opts:  NormalizeOptions{SortColumns: true},
+			want:  "select distinct a, b from t",
+		},
+		{
+			name:  "sort subquery projection",
+			query: "SELECT b, a FROM t WHERE id IN (SELECT d, c FROM u)",
+			opts:  NormalizeOptions{SortColumns: true},
+			want:  "select a, b from t where id in ( select c, d from u ) ",
+		},
+		{
+			// The documented limitations: function arguments are sorted
+			// although their order matters, and projections with
+			// expressions are left alone
+			name:  "sort columns with expressions",
+			query: "SELECT name, COUNT(*), id FROM users WHERE x = COALESCE(b, a)",
+			opts:  NormalizeOptions{SortColumns: true},
+			want:  "select name, count ( * ) , id from users where x = coalesce ( a, b ) ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeQuery(tt.query, tt.opts); got != tt.want {
+				t.Errorf("NormalizeQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCase(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      string
+		duplicate bool
+	}{
+		{
+			name:      "different constants",
+			a:         "SELECT CASE WHEN status = 1 THEN 'new' WHEN status = 2 THEN 'paid' ELSE 'other' END FROM orders",
+			b:         "SELECT CASE WHEN status = 3 THEN 'sent' WHEN status = 4 THEN 'done' ELSE 'x' END FROM orders",
+			duplicate: true,
+		},
+		{
+			name:      "literals touching keywords",
+			a:         "SELECT CASE WHEN status = 1 THEN 'new' ELSE 'other' END FROM orders",
+			b:         "SELECT CASE WHEN status=3 THEN'sent'ELSE'x'END FROM orders",
+			duplicate: true,
+		},
+		{
+			name:      "nested case",
+			a:         "SELECT CASE WHEN a = 1 THEN CASE WHEN b = 2 THEN 'x' ELSE 'y' END ELSE 'z' END FROM t",
+			b:         "SELECT CASE WHEN a=5 THEN CASE WHEN b=6 THEN'p'ELSE'q'END ELSE'r'END FROM t",
+			duplicate: true,
+		},
+		{
+			name:      "else null",
+			a:         "SELECT CASE WHEN total > 100 THEN 'big' ELSE NULL END FROM orders",
+			b:         "SELECT CASE WHEN total > 500 THEN 'huge' END FROM orders",
+			duplicate: true,
+		},
+		{
+			name: "different operand",
+			a:    "SELECT CASE status WHEN 1 THEN 'a' END FROM t",
+			b:    "SELECT CASE kind WHEN 1 THEN 'a' END FROM t",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := Normalize(tt.a), Normalize(tt.b)
+			if (a == b) != tt.duplicate {
+				t.Errorf("Normalize(%q) = %q and Normalize(%q) = %q, want duplicate %v", tt.a, a, tt.b, b, tt.duplicate)
+			}
+		})
+	}
+}
+
+func TestNormalizeTableSuffixes(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "year suffix",
+			query: "SELECT SUM(total) FROM orders_2023 WHERE customer_id = 7",
+			want:  "select sum ( total ) from orders_N where customer_id = N",
+		},
+		{
+			name:  "date suffix",
+			query: "SELECT SUM(total) FROM orders_2024_06 WHERE customer_id = 3",
+			want:  "select sum ( total ) from orders_N where customer_id = N",
+		},
+		{
+			name:  "insert and join",
+			query: "INSERT INTO archive_7 SELECT * FROM orders_12 o JOIN items_12 i ON i.order_id = o.id",
+			want:  "insert into archive_N select * from orders_N o join items_N i on i.order_id = o.id",
+		},
+		{
+			name:  "update",
+			query: "UPDATE tenant_42 SET name = 'x'",
+			want:  "update tenant_N set name = S",
+		},
+		{
+			name:  "schema suffix kept",
+			query: "SELECT * FROM shard_7.events_12",
+			want:  "select * from shard_7.events_N",
+		},
+		{
+			name:  "digits inside the name kept",
+			query: "SELECT * FROM log_1_users",
+			want:  "select * from log_1_users",
+		},
+		{
+			name:  "columns kept",
+			query: "SELECT total_2023 FROM orders WHERE col_1 = 1",
+			want:  "select total_2023 from orders where col_1 = N",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeQuery(tt.query, NormalizeOptions{NormalizeTableSuffixes: true})
+			if got != tt.want {
+				t.Errorf("NormalizeQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	a, b := "SELECT * FROM orders_2023 WHERE id = 1", "SELECT * FROM orders_2024 WHERE id = 2"
+	if Normalize(a) == Normalize(b) {
+		t.Errorf("Normalize(%q) and Normalize(%q) are equal without NormalizeTableSuffixes", a, b)
+	}
+}
+
+// corpusFiles are the fixtures at the root of the repository, used as a
+// realistic corpus by the benchmarks.
+var corpusFiles = []string{"test.php", "test.py", "test.java", "test-delimiter.sql", "test-slow-query.log"}
+
+// readCorpus returns the contents of the corpus files, keyed by path.
+func readCorpus(b *testing.B) map[string]string {
+	b.Helper()
+	corpus := make(map[string]string)
+	for _, name := range corpusFiles {
+		path := filepath.Join("..", "..", name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		corpus[path] = string(data)
+	}
+	return corpus
+}
+
+func BenchmarkNormalizeQuery(b *testing.B) {
+	var queries []string
+	for path, text := range readCorpus(b) {
+		for _, match := range extractQueries(path, text, Config{}) {
+			queries = append(queries, match.Text)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, query := range queries {
+			NormalizeQuery(query, NormalizeOptions{})
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(queries)), "ns/query")
+}
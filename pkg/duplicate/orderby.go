@@ -0,0 +1,103 @@
+package duplicate
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// orderByEndRe matches the clauses that end an ORDER BY list, whether of a
+// query or of a window such as OVER ( ORDER BY a ROWS ... ).
+var orderByEndRe = regexp.MustCompile(`^(?:limit|offset|fetch|for|union|intersect|except|rows|range|groups)\b`)
+
+// normalizeOrderBy rewrites the ORDER BY lists of a normalized query so that
+// lists only differing in an explicit ASC, which is the default direction,
+// compare equal. With sortKeys, the keys are sorted too, each keeping its
+// direction, so ORDER BY a, b DESC and ORDER BY b DESC, a compare equal.
+//
+// This is a heuristic: sorting the keys changes the order of the results,
+// and an ORDER BY inside a string literal is rewritten like any other.
+func normalizeOrderBy(query string, sortKeys bool) string {
+	const keyword = "order by "
+	var b strings.Builder
+	for {
+		i := strings.Index(query, keyword)
+		if i < 0 {
+			b.WriteString(query)
+			return b.String()
+		}
+		start := i + len(keyword)
+		if i > 0 && isIdentByte(query[i-1]) {
+			b.WriteString(query[:start])
+			query = query[start:]
+			continue
+		}
+
+		end := orderByEnd(query, start)
+		keys := splitOrderBy(query[start:end])
+		for k, key := range keys {
+			keys[k] = dropAsc(key)
+		}
+		if sortKeys {
+			sort.Strings(keys)
+		}
+
+		b.WriteString(query[:start])
+		b.WriteString(strings.Join(keys, ", "))
+		query = query[end:]
+	}
+}
+
+// orderByEnd returns the position just after the ORDER BY list starting at
+// start, before any trailing space.
+func orderByEnd(query string, start int) int {
+	depth := 0
+	end := len(query)
+loop:
+	for i := start; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				end = i
+				break loop
+			}
+			depth--
+		case c == ';' && depth == 0:
+			end = i
+			break loop
+		case depth == 0 && query[i-1] == ' ' && orderByEndRe.MatchString(query[i:]):
+			end = i
+			break loop
+		}
+	}
+	return start + len(strings.TrimRight(query[start:end], " "))
+}
+
+// splitOrderBy splits an ORDER BY list at the commas outside parentheses.
+func splitOrderBy(list string) []string {
+	var keys []string
+	depth, start := 0, 0
+	for i := 0; i < len(list); i++ {
+		switch {
+		case list[i] == '(':
+			depth++
+		case list[i] == ')':
+			depth--
+		case depth == 0 && strings.HasPrefix(list[i:], ", "):
+			keys = append(keys, list[start:i])
+			start = i + len(", ")
+		}
+	}
+	return append(keys, list[start:])
+}
+
+// dropAsc removes the explicit ASC of an ORDER BY key, keeping any NULLS
+// FIRST or NULLS LAST after it.
+func dropAsc(key string) string {
+	if strings.HasSuffix(key, " asc") {
+		return strings.TrimSuffix(key, " asc")
+	}
+	return strings.Replace(key, " asc nulls ", " nulls ", 1)
+}
@@ -0,0 +1,107 @@
+package duplicate
+
+import "testing"
+
+func TestNormalizeOrderBy(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		opts  NormalizeOptions
+		want  string
+	}{
+		{
+			name:  "explicit asc",
+			query: "SELECT * FROM t ORDER BY a ASC, b",
+			opts:  NormalizeOptions{NormalizeOrderBy: true},
+			want:  "select * from t order by a, b",
+		},
+		{
+			name:  "desc kept",
+			query: "SELECT * FROM t ORDER BY a ASC, b DESC",
+			opts:  NormalizeOptions{NormalizeOrderBy: true},
+			want:  "select * from t order by a, b desc",
+		},
+		{
+			name:  "nulls last kept",
+			query: "SELECT * FROM t ORDER BY a ASC NULLS LAST",
+			opts:  NormalizeOptions{NormalizeOrderBy: true},
+			want:  "select * from t order by a nulls last",
+		},
+		{
+			name:  "keys sorted before limit",
+			query: "SELECT * FROM t ORDER BY b DESC, a ASC LIMIT 10",
+			opts:  NormalizeOptions{SortOrderBy: true},
+			want:  "select * from t order by a, b desc limit N",
+		},
+		{
+			name:  "window order by",
+			query: "SELECT ROW_NUMBER() OVER (PARTITION BY x ORDER BY z, y ASC ROWS BETWEEN 1 PRECEDING AND CURRENT ROW) FROM t",
+			opts:  NormalizeOptions{SortOrderBy: true},
+			want:  "select row_number ( ) over ( partition by x order by y, z rows between N preceding and current row ) from t",
+		},
+		{
+			name:  "function keys kept whole",
+			query: "SELECT * FROM t ORDER BY FIELD(id, 1, 2) ASC, a",
+			opts:  NormalizeOptions{SortOrderBy: true},
+			want:  "select * from t order by a, field ( id, N, N )",
+		},
+		{
+			name:  "off by default",
+			query: "SELECT * FROM t ORDER BY b ASC, a",
+			want:  "select * from t order by b asc, a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeQuery(tt.query, tt.opts); got != tt.want {
+				t.Errorf("NormalizeQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeOrderByGroups(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      string
+		opts      NormalizeOptions
+		duplicate bool
+	}{
+		{
+			name:      "explicit and implicit asc",
+			a:         "SELECT * FROM products ORDER BY category ASC, price DESC",
+			b:         "SELECT * FROM products ORDER BY category, price DESC",
+			opts:      NormalizeOptions{NormalizeOrderBy: true},
+			duplicate: true,
+		},
+		{
+			name: "reordered keys without sorting",
+			a:    "SELECT * FROM products ORDER BY category, price DESC",
+			b:    "SELECT * FROM products ORDER BY price DESC, category",
+			opts: NormalizeOptions{NormalizeOrderBy: true},
+		},
+		{
+			name:      "reordered keys",
+			a:         "SELECT * FROM products ORDER BY category ASC, price DESC",
+			b:         "SELECT * FROM products ORDER BY price DESC, category",
+			opts:      NormalizeOptions{SortOrderBy: true},
+			duplicate: true,
+		},
+		{
+			name: "different directions",
+			a:    "SELECT * FROM products ORDER BY category, price DESC",
+			b:    "SELECT * FROM products ORDER BY category, price",
+			opts: NormalizeOptions{SortOrderBy: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := NormalizeQuery(tt.a, tt.opts), NormalizeQuery(tt.b, tt.opts)
+			if (a == b) != tt.duplicate {
+				t.Errorf("NormalizeQuery(%q) = %q and NormalizeQuery(%q) = %q, want duplicate %v", tt.a, a, tt.b, b, tt.duplicate)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package duplicate
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// paramRe matches the literals of a query, and the placeholder
+// replaceDollarQuotes leaves for dollar-quoted ones.
+var paramRe = regexp.MustCompile(singleQuotedPattern + "|" + doubleQuotedPattern + `|\x01|` + numberPattern)
+
+// queryParams returns the string and number literals of query in the order
+// they appear, i.e. the values NormalizeQuery collapses to S and N.
+func queryParams(query string) []string {
+	query, dollarLiterals := replaceDollarQuotes(stripComments(query), "\x01")
+	params := paramRe.FindAllString(query, -1)
+	for i, param := range params {
+		if param == "\x01" {
+			params[i], dollarLiterals = dollarLiterals[0], dollarLiterals[1:]
+		}
+	}
+	return params
+}
+
+// DistinctParams returns the distinct sets of literal values the queries of
+// a group were written with, sorted. Each entry lists the values of one
+// occurrence separated by commas, so a group with as many entries as
+// occurrences runs with different values everywhere (e.g. an N+1 lookup),
+// while a single entry means the same constants are repeated.
+func DistinctParams(results []QueryResult) []string {
+	seen := make(map[string]bool)
+	var distinct []string
+	for _, result := range results {
+		params := strings.Join(queryParams(result.Query), ", ")
+		if !seen[params] {
+			seen[params] = true
+			distinct = append(distinct, params)
+		}
+	}
+	sort.Strings(distinct)
+	return distinct
+}
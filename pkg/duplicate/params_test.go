@@ -0,0 +1,56 @@
+package duplicate
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestQueryParams(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "numbers",
+			query: "SELECT * FROM t2 WHERE a = 42 AND b = 3.14 AND col2 = 7",
+			want:  []string{"42", "3.14", "7"},
+		},
+		{
+			name:  "exponents",
+			query: "SELECT * FROM t WHERE a = 1e5 AND b = 2.5E-3 AND x1e5 = 3.0e+2",
+			want:  []string{"1e5", "2.5E-3", "3.0e+2"},
+		},
+		{
+			name:  "strings",
+			query: `SELECT * FROM t WHERE a = 'a, (b)' AND b = 'it''s' AND c = "x\"y"`,
+			want:  []string{"'a, (b)'", "'it''s'", `"x\"y"`},
+		},
+		{
+			name:  "dollar quotes",
+			query: "SELECT $$it's$$, 1 FROM t",
+			want:  []string{"$$it's$$", "1"},
+		},
+		{
+			name:  "comments",
+			query: "SELECT * FROM t -- 'x' 1\nWHERE a = 2 /* 3 */",
+			want:  []string{"2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := queryParams(tt.query)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queryParams(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+
+			// Every literal normalization collapses is a parameter
+			literals := len(regexp.MustCompile(`\b[NS]\b`).FindAllString(Normalize(tt.query), -1))
+			if literals != len(got) {
+				t.Errorf("queryParams(%q) = %q, want the %d literals of %q", tt.query, got, literals, Normalize(tt.query))
+			}
+		})
+	}
+}
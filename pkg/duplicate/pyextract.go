@@ -0,0 +1,170 @@
+package duplicate
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The regular expressions used by FindPythonQueries, compiled once.
+var (
+	// pyExecuteRe matches the end of the text before the first argument of
+	// an execute or executemany call.
+	pyExecuteRe = regexp.MustCompile(`\.(?:execute|executemany)\s*\(\s*$`)
+	// pyPlaceholderRe matches the DB-API placeholders %s, %(name)s and
+	// :name, and str.format and f-string fields. SQL string literals and ::
+	// casts are matched too so they can be left alone.
+	pyPlaceholderRe = regexp.MustCompile(singleQuotedPattern + `|::|%\(\w+\)[sd]|%[sd]|:[A-Za-z_]\w*|\{[^{}]*\}`)
+)
+
+// pyEscapes are the escape sequences of non-raw Python strings that can
+// appear in SQL.
+var pyEscapes = strings.NewReplacer(`\\`, `\`, `\'`, `'`, `\"`, `"`, `\n`, "\n", `\t`, "\t", "\\\n", "")
+
+// pyExecuteLookback bounds how much text before a literal is searched for
+// an execute call.
+const pyExecuteLookback = 64
+
+// FindPythonQueries returns the SQL found in Python source: the arguments of
+// execute and executemany calls, and any other string expression starting
+// with a SQL statement, such as a triple-quoted string or a query assigned
+// to a variable. Adjacent literals are joined, as Python does, including
+// across lines inside parentheses. Placeholders such as %s, %(name)s, :name
+// and {name} become ? placeholders.
+func FindPythonQueries(text string) []Match {
+	var result []Match
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '#':
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth = max(depth-1, 0)
+		case c == '"' || c == '\'':
+			start := pyLiteralStart(text, i)
+			value, end, ok := readPyStringExpr(text, start, depth > 0)
+			if !ok {
+				continue
+			}
+
+			query := strings.TrimSpace(value)
+			isCall := pyExecuteRe.MatchString(text[max(0, start-pyExecuteLookback):start])
+			if query != "" && (isCall || statementStartRe.MatchString(query[:min(len(query), maxKeywordLength)])) {
+				result = append(result, Match{Text: pyPlaceholders(query), Offset: start})
+			}
+			i = end - 1
+		}
+	}
+	return result
+}
+
+// pyLiteralStart returns the position of the string prefix, such as r or
+// rb, before the quote at pos, or pos if it has none.
+func pyLiteralStart(text string, pos int) int {
+	start := pos
+	for start > 0 && pos-start < 2 && strings.IndexByte("rRbBuUfF", text[start-1]) >= 0 {
+		start--
+	}
+	if start > 0 && isIdentByte(text[start-1]) {
+		return pos
+	}
+	return start
+}
+
+// readPyStringExpr reads one or more adjacent string literals starting at
+// pos and returns their joined value and the position just after the last
+// one. Literals on different lines are only joined inside parentheses.
+func readPyStringExpr(text string, pos int, inParens bool) (string, int, bool) {
+	var b strings.Builder
+	for {
+		value, end, ok := readPyLiteral(text, pos)
+		if !ok {
+			return "", 0, false
+		}
+		b.WriteString(value)
+
+		next := end
+		for next < len(text) {
+			if c := text[next]; c == ' ' || c == '\t' || c == '\r' || inParens && c == '\n' {
+				next++
+			} else if c == '\\' && next+1 < len(text) && text[next+1] == '\n' {
+				next += 2
+			} else {
+				break
+			}
+		}
+		if next >= len(text) {
+			return b.String(), end, true
+		}
+		if !isPyLiteral(text, next) {
+			return b.String(), end, true
+		}
+		pos = next
+	}
+}
+
+// isPyLiteral reports whether a string literal, possibly prefixed, starts at
+// pos.
+func isPyLiteral(text string, pos int) bool {
+	for i := pos; i < len(text) && i-pos <= 2; i++ {
+		if text[i] == '"' || text[i] == '\'' {
+			return true
+		}
+		if strings.IndexByte("rRbBuUfF", text[i]) < 0 {
+			return false
+		}
+	}
+	return false
+}
+
+// readPyLiteral reads the single, double or triple quoted literal starting
+// at pos, with an optional prefix, and returns its value and the position
+// just after it.
+func readPyLiteral(text string, pos int) (string, int, bool) {
+	i := pos
+	raw := false
+	for i < len(text) && text[i] != '"' && text[i] != '\'' {
+		if text[i] == 'r' || text[i] == 'R' {
+			raw = true
+		}
+		i++
+	}
+	if i >= len(text) {
+		return "", 0, false
+	}
+
+	quote := text[i : i+1]
+	if strings.HasPrefix(text[i:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	bodyStart := i + len(quote)
+	for j := bodyStart; j < len(text); j++ {
+		switch {
+		case text[j] == '\\':
+			j++
+		case text[j] == '\n' && len(quote) == 1:
+			return "", 0, false
+		case strings.HasPrefix(text[j:], quote):
+			value := text[bodyStart:j]
+			if !raw {
+				value = pyEscapes.Replace(value)
+			}
+			return value, j + len(quote), true
+		}
+	}
+	return "", 0, false
+}
+
+// pyPlaceholders replaces the placeholders of query with ?, leaving SQL
+// string literals and :: casts alone.
+func pyPlaceholders(query string) string {
+	return pyPlaceholderRe.ReplaceAllStringFunc(query, func(m string) string {
+		if m[0] == '\'' || m == "::" {
+			return m
+		}
+		return string(concatPlaceholder)
+	})
+}
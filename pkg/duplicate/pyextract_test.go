@@ -0,0 +1,96 @@
+package duplicate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindPythonQueries(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Match
+	}{
+		{
+			name: "execute argument",
+			text: `cur.execute("SELECT * FROM t WHERE id = %s", (id,))`,
+			want: []Match{{Text: "SELECT * FROM t WHERE id = ?", Offset: 12}},
+		},
+		{
+			name: "execute argument that isn't a statement",
+			text: `cur.executemany("x = %s", rows)`,
+			want: []Match{{Text: "x = ?", Offset: 16}},
+		},
+		{
+			name: "raw string prefix",
+			text: `q = r"SELECT * FROM t WHERE a ~ '\d+'"`,
+			want: []Match{{Text: `SELECT * FROM t WHERE a ~ '\d+'`, Offset: 4}},
+		},
+		{
+			name: "two letter prefix",
+			text: `q = rb'SELECT 1'`,
+			want: []Match{{Text: "SELECT 1", Offset: 4}},
+		},
+		{
+			name: "prefix letters ending an identifier",
+			text: `q = bar"SELECT 1"`,
+			want: []Match{{Text: "SELECT 1", Offset: 7}},
+		},
+		{
+			name: "escapes",
+			text: `q = "SELECT * FROM t WHERE a = \"x\"\n"`,
+			want: []Match{{Text: `SELECT * FROM t WHERE a = "x"`, Offset: 4}},
+		},
+		{
+			name: "triple quotes",
+			text: "q = \"\"\"\n    SELECT *\n    FROM t\n    WHERE id = %(id)s\n\"\"\"",
+			want: []Match{{Text: "SELECT *\n    FROM t\n    WHERE id = ?", Offset: 4}},
+		},
+		{
+			name: "quotes inside triple quotes",
+			text: "q = '''SELECT 'x' FROM t'''",
+			want: []Match{{Text: "SELECT 'x' FROM t", Offset: 4}},
+		},
+		{
+			name: "adjacent literals in parentheses",
+			text: "cur.execute(\n    \"SELECT * FROM t \"\n    'WHERE id = :id'\n)",
+			want: []Match{{Text: "SELECT * FROM t WHERE id = ?", Offset: 17}},
+		},
+		{
+			name: "adjacent literals on separate lines outside parentheses",
+			text: "q = \"SELECT * FROM t \"\n\"WHERE id = 1\"",
+			want: []Match{{Text: "SELECT * FROM t", Offset: 4}},
+		},
+		{
+			name: "adjacent literals joined with a backslash",
+			text: "q = \"SELECT * FROM t \" \\\n    \"WHERE id = 1\"",
+			want: []Match{{Text: "SELECT * FROM t WHERE id = 1", Offset: 4}},
+		},
+		{
+			name: "format fields",
+			text: `q = f"SELECT * FROM {table} WHERE id = {id}"`,
+			want: []Match{{Text: "SELECT * FROM ? WHERE id = ?", Offset: 4}},
+		},
+		{
+			name: "casts and quoted literals left alone",
+			text: `q = "SELECT a::int, 'x:y', '{z}', '%s' FROM t WHERE b = :b AND c = %d"`,
+			want: []Match{{Text: "SELECT a::int, 'x:y', '{z}', '%s' FROM t WHERE b = ? AND c = ?", Offset: 4}},
+		},
+		{
+			name: "comments and other strings",
+			text: "print(\"hello\")  # \"SELECT 1\"\nname = 'select'",
+		},
+		{
+			name: "unterminated literal",
+			text: "q = \"SELECT 1\nx = 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindPythonQueries(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindPythonQueries(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
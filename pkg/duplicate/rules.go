@@ -0,0 +1,55 @@
+package duplicate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Rule is a custom normalization step replacing the matches of Pattern
+// with Replacement, which may refer to submatches like $1.
+type Rule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// rulesFile is the format of the file read by LoadRules.
+type rulesFile struct {
+	// Replace drops the built-in replacements instead of running the rules
+	// after them.
+	Replace bool `json:"replace"`
+	Rules   []struct {
+		Pattern     string `json:"pattern"`
+		Replacement string `json:"replacement"`
+	} `json:"rules"`
+}
+
+// LoadRules reads custom normalization rules from a JSON file such as
+//
+//	{"replace": false, "rules": [{"pattern": "\\bifnull\\b", "replacement": "coalesce"}]}
+//
+// and returns them in order, along with whether they replace the built-in
+// replacements of NormalizeQuery. Every pattern is compiled up front, so a
+// bad one is reported before anything is scanned.
+func LoadRules(path string) ([]Rule, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading rules file: %v", err)
+	}
+
+	var file rulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, false, fmt.Errorf("error parsing rules file %s: %v", path, err)
+	}
+
+	rules := make([]Rule, len(file.Rules))
+	for i, rule := range file.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, false, fmt.Errorf("error in rules file %s: rule %d has an invalid pattern %q: %v", path, i+1, rule.Pattern, err)
+		}
+		rules[i] = Rule{Pattern: re, Replacement: rule.Replacement}
+	}
+	return rules, file.Replace, nil
+}
@@ -0,0 +1,85 @@
+package duplicate
+
+import (
+	"sort"
+	"strings"
+)
+
+// FindSimilar merges the groups returned by ProcessFiles whose normalized
+// forms are at least threshold similar, measured as the Jaccard index of
+// their whitespace separated tokens. Each merged group is keyed by its
+// representative, the most frequent normalized query in the cluster, and
+// groups with fewer than minCount occurrences are dropped.
+//
+// Every distinct normalized query is compared against every cluster found
+// so far, which is O(n^2) in the number of distinct queries. Expect it to be
+// much slower than exact matching on large codebases.
+func FindSimilar(groups map[string][]QueryResult, threshold float64, minCount int) map[string][]QueryResult {
+	// Visit the most common queries first so they become representatives
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(groups[keys[i]]) != len(groups[keys[j]]) {
+			return len(groups[keys[i]]) > len(groups[keys[j]])
+		}
+		return keys[i] < keys[j]
+	})
+
+	type cluster struct {
+		key    string
+		tokens map[string]bool
+	}
+
+	var clusters []cluster
+	similar := make(map[string][]QueryResult)
+	for _, key := range keys {
+		tokens := tokenSet(key)
+
+		assigned := false
+		for _, c := range clusters {
+			if jaccard(tokens, c.tokens) >= threshold {
+				similar[c.key] = append(similar[c.key], groups[key]...)
+				assigned = true
+				break
+			}
+		}
+
+		if !assigned {
+			clusters = append(clusters, cluster{key: key, tokens: tokens})
+			similar[key] = groups[key]
+		}
+	}
+
+	for key, value := range similar {
+		if len(value) < minCount {
+			delete(similar, key)
+		}
+	}
+	return similar
+}
+
+func tokenSet(query string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, token := range strings.Fields(query) {
+		tokens[token] = true
+	}
+	return tokens
+}
+
+// jaccard returns the size of the intersection of a and b divided by the
+// size of their union.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	intersection := 0
+	for token := range a {
+		if b[token] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
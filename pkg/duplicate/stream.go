@@ -0,0 +1,124 @@
+package duplicate
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Files larger than streamThreshold are read in chunks of about
+// streamChunkSize bytes rather than all at once, so big SQL dumps don't have
+// to fit in memory. Each chunk is scanned together with the last
+// streamOverlap bytes of the previous one, so a statement crossing a chunk
+// boundary is still found whole as long as it is shorter than the overlap.
+// Longer statements are cut at the end of their chunk, and the context
+// lines of a query stop at the chunk it was found in. They are variables so
+// tests can stream small files.
+var (
+	streamThreshold int64 = 64 << 20
+	streamChunkSize       = 16 << 20
+	streamOverlap         = 1 << 20
+)
+
+// analyzeStream is AnalyzeFile for large files, reading them chunk by chunk.
+// Chunks end at line breaks so multi-byte characters are never split.
+func analyzeStream(path string, config Config) ([]QueryResult, error) {
+	releaseFile := config.acquireFile()
+	defer releaseFile()
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+
+	// UTF-16 can't be split at line breaks as easily, so read it whole
+	if bom, _ := reader.Peek(2); bytes.Equal(bom, utf16LEBOM) || bytes.Equal(bom, utf16BEBOM) {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file: %w", err)
+		}
+		release := config.acquireCPU()
+		defer release()
+		text, _ := decodeText(data)
+		text = normalizeLineBreaks(text)
+		return newResults(path, text, extractQueries(path, text, config), 1, config), nil
+	}
+
+	var results []QueryResult
+	var carry string
+	firstLine := 1
+	skip := 0 // the end of the last match taken, as an offset into the next buffer
+	warned := false
+	for {
+		chunk, err := readChunk(reader, streamChunkSize)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("error reading file: %w", err)
+		}
+		eof := err == io.EOF
+
+		release := config.acquireCPU()
+		text, ok := decodeText(chunk)
+		if !ok && !warned {
+			config.warnf("%s is not valid UTF-8, reading it as Latin-1", path)
+			warned = true
+		}
+		buffer := carry + normalizeLineBreaks(text)
+
+		// Keep the matches starting before the overlap, which is scanned
+		// again with the next chunk, and the next buffer starts at a line
+		// break so quotes earlier on the line are seen
+		cut := len(buffer)
+		if !eof {
+			cut = max(len(buffer)-streamOverlap, 0)
+			if n := strings.LastIndexByte(buffer[:cut], '\n'); n >= 0 {
+				cut = n + 1
+			}
+		}
+
+		var matches []Match
+		end := 0
+		for _, match := range extractQueries(path, buffer, config) {
+			if match.Offset < skip || match.Offset >= cut {
+				continue
+			}
+			matches = append(matches, match)
+			end = max(end, match.Offset+len(match.Text))
+		}
+		results = append(results, newResults(path, buffer, matches, firstLine, config)...)
+		release()
+
+		if eof {
+			return results, nil
+		}
+		firstLine += strings.Count(buffer[:cut], "\n")
+		skip = max(end-cut, 0)
+		carry = buffer[cut:]
+	}
+}
+
+// readChunk reads at least size bytes from r, unless it ends first, and then
+// up to the next line break. It returns io.EOF once r is exhausted.
+func readChunk(r *bufio.Reader, size int) ([]byte, error) {
+	chunk := make([]byte, size)
+	n, err := io.ReadFull(r, chunk)
+	chunk = chunk[:n]
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return chunk, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rest, err := r.ReadBytes('\n')
+	chunk = append(chunk, rest...)
+	if err == io.EOF {
+		if _, peekErr := r.Peek(1); peekErr == io.EOF {
+			return chunk, io.EOF
+		}
+	}
+	return chunk, err
+}
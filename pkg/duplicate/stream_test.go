@@ -0,0 +1,65 @@
+package duplicate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setStreamSizes lowers the streaming sizes for the duration of a test.
+func setStreamSizes(t *testing.T, threshold int64, chunkSize, overlap int) {
+	t.Helper()
+	oldThreshold, oldChunkSize, oldOverlap := streamThreshold, streamChunkSize, streamOverlap
+	streamThreshold, streamChunkSize, streamOverlap = threshold, chunkSize, overlap
+	t.Cleanup(func() {
+		streamThreshold, streamChunkSize, streamOverlap = oldThreshold, oldChunkSize, oldOverlap
+	})
+}
+
+func TestAnalyzeFileStreamed(t *testing.T) {
+	// Statements of varying length, some spanning lines, so many of them
+	// cross a chunk boundary
+	var b strings.Builder
+	for i := 0; i < 2000; i++ {
+		switch i % 4 {
+		case 0:
+			fmt.Fprintf(&b, "INSERT INTO items (id, name) VALUES (%d, 'item %d');\n", i, i)
+		case 1:
+			fmt.Fprintf(&b, "SELECT *\n  FROM orders\n  WHERE id = %d\n    AND note = 'a;b';\n", i)
+		case 2:
+			fmt.Fprintf(&b, "-- statement %d\nUPDATE users SET name = '%s' WHERE id = %d;\n", i, strings.Repeat("x", i%97), i)
+		default:
+			fmt.Fprintf(&b, "DELETE FROM sessions WHERE id = %d;\n\n", i)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{}
+	want, err := AnalyzeFile(path, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 2000 {
+		t.Fatalf("AnalyzeFile found %d queries, want 2000", len(want))
+	}
+
+	setStreamSizes(t, 1<<10, 4<<10, 1<<10)
+	got, err := AnalyzeFile(path, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("streamed AnalyzeFile found %d queries, want the %d found in memory", len(got), len(want))
+		for i := range min(len(got), len(want)) {
+			if !reflect.DeepEqual(got[i], want[i]) {
+				t.Fatalf("first difference at query %d: got %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package queries
+
+import (
+	"database/sql"
+	"strconv"
+)
+
+func openOrders(db *sql.DB, customerID string) (*sql.Rows, error) {
+	return db.Query("SELECT * FROM orders WHERE customer_id = " + customerID + " AND status = 'open'")
+}
+
+// Duplicate of openOrders
+func openOrdersOf(db *sql.DB, c Customer) (*sql.Rows, error) {
+	return db.Query("SELECT * FROM orders " +
+		"WHERE customer_id = " + c.ID() + " AND status = 'open'")
+}
+
+func order(db *sql.DB, id int) *sql.Row {
+	return db.QueryRow("SELECT * FROM orders WHERE id = " + strconv.Itoa(id))
+}
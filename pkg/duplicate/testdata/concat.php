@@ -0,0 +1,9 @@
+<?php
+$a = "SELECT * FROM users " . "WHERE id = " . $id;
+$b = 'SELECT * FROM users WHERE id = ' . $user->id;  // Duplicate of $a
+$c = "SELECT * FROM users WHERE id = $id";  // Duplicate of $a
+$d = "SELECT * FROM users "
+    . "WHERE id = {$row['id']}";  // Duplicate of $a
+$e = "SELECT * FROM orders WHERE customer_id = " . $customer->getId() . " AND status = 'open'";
+$sql = "SELECT * FROM items";
+$sql .= " WHERE id = 1";  // Not joined: .= appends to a variable
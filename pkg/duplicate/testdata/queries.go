@@ -0,0 +1,45 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+)
+
+func findUser(db *sql.DB, id int) *sql.Row {
+	return db.QueryRow("SELECT * FROM users WHERE id = ?", id)
+}
+
+// Duplicate of findUser, with a raw string
+func loadUser(ctx context.Context, db *sql.DB, id int) *sql.Row {
+	return db.QueryRowContext(ctx, `
+		SELECT *
+		FROM users
+		WHERE id = ?`, id)
+}
+
+// Duplicate of findUser, with literals joined with +
+func getUser(db *sql.DB, id int) (*sql.Rows, error) {
+	return db.Query("SELECT * FROM users "+
+		"WHERE id = ?", id)
+}
+
+func deleteSessions(ctx context.Context, db *sql.DB, userID int) error {
+	_, err := db.ExecContext(context.WithValue(ctx, "op", []int{1, 2}), "DELETE FROM sessions WHERE user_id = ?", userID)
+	return err
+}
+
+func listOrders(tx *sql.Tx, status string) (*sql.Rows, error) {
+	return tx.Query(`SELECT id, total FROM orders WHERE status = "open"`)
+}
+
+// Not queries: the arguments aren't string literals
+func search(db *sql.DB, r *http.Request, query string) {
+	_ = r.URL.Query()
+	db.Query(query)
+	db.Exec(buildQuery(r))
+}
+
+func buildQuery(r *http.Request) string {
+	return r.URL.Query().Get("q")
+}
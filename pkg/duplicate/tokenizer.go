@@ -0,0 +1,241 @@
+package duplicate
+
+import (
+	"regexp"
+	"strings"
+)
+
+// statementStartRe matches the keywords a SQL statement can start with. It
+// is anchored so it can be tried at every word of the text.
+var statementStartRe = regexp.MustCompile(`^(?i:select\s|` +
+	`(?:insert|replace)\s+(?:ignore\s+)?into\s|` +
+	"update\\s+[\\w.`\"]+\\s+set\\s|" +
+	`delete\s+from\s|` +
+	`create\s+(?:table|database|view|(?:unique\s+)?index)\s|` +
+	`alter\s+table\s|` +
+	`drop\s+(?:table|database|view|index)\s|` +
+	`truncate\s+(?:table\s+)?\w|` +
+	`with\s+(?:recursive\s+)?\w+\s*(?:\([^)]*\)\s*)?as\s*(?:(?:not\s+)?materialized\s*)?\(|` +
+	`call\s+[\w.]+\s*\()`)
+
+// routineStartRe matches the start of a stored program. Its body holds
+// semicolons, so it is only taken as one statement when another delimiter
+// ends it.
+var routineStartRe = regexp.MustCompile(`^(?i:create\s+(?:or\s+replace\s+)?(?:definer\s*=\s*\S+\s+)?(?:procedure|function|trigger|event)\s)`)
+
+// delimiterDirectiveRe matches the DELIMITER lines of MySQL scripts, with
+// the new delimiter in the first submatch.
+var delimiterDirectiveRe = regexp.MustCompile(`(?im)^[ \t]*delimiter[ \t]+(\S+)[ \t]*\r?$`)
+
+// maxKeywordLength bounds how much text is handed to the statement start
+// pattern for each candidate word.
+const maxKeywordLength = 256
+
+// FindSQLStatements returns the SQL statements found in text, like
+// FindSQLQueries, but finds their boundaries with a small tokenizer instead
+// of a single regular expression. A statement starts at a SQL keyword and
+// ends at a semicolon outside of quotes, comments and parentheses. When the
+// statement sits in a string literal of the host language, such as a PHP
+// "..." string, it also ends with that string. To keep prose from being read
+// as SQL, keywords in host language line comments and in the middle of
+// strings are skipped, and statements outside of strings also end at a
+// blank line, unless another delimiter is in use.
+func FindSQLStatements(text string) []Match {
+	return FindSQLStatementsDelimited(text, ";")
+}
+
+// FindSQLStatementsDelimited is FindSQLStatements with statements ending at
+// delimiter rather than a semicolon. A delimiter made of letters, such as
+// the GO of SQL Server scripts, has to be on a line of its own, and
+// semicolons still end statements. DELIMITER lines in text change the
+// delimiter from there on. Stored programs such as CREATE PROCEDURE are
+// read as one statement while the delimiter isn't a semicolon.
+func FindSQLStatementsDelimited(text, delimiter string) []Match {
+	directives := delimiterDirectiveRe.FindAllStringSubmatchIndex(text, -1)
+
+	var result []Match
+	for i := 0; i < len(text); i++ {
+		if !isWordStart(text, i) {
+			continue
+		}
+
+		// The delimiter set by the last DELIMITER line before i
+		for len(directives) > 0 && directives[0][0] < i {
+			delimiter = text[directives[0][2]:directives[0][3]]
+			directives = directives[1:]
+		}
+
+		head := text[i:min(len(text), i+maxKeywordLength)]
+		isStart := statementStartRe.MatchString(head) || delimiter != ";" && routineStartRe.MatchString(head)
+		if !isStart || inLineComment(text, i) {
+			i = skipWord(text, i)
+			continue
+		}
+
+		host, ok := hostQuote(text, i)
+		if !ok {
+			i = skipWord(text, i)
+			continue
+		}
+
+		end, terminated := statementEnd(text, i, host, delimiter)
+		statement := strings.TrimSpace(text[i:end])
+		if host != 0 {
+			// Quotes escaped for the host string are plain quotes in SQL
+			statement = strings.ReplaceAll(statement, `\`+string(host), string(host))
+		}
+		if statement != "" && terminated {
+			result = append(result, Match{Text: statement, Offset: i})
+		}
+		i = end
+	}
+	return result
+}
+
+// statementEnd scans the statement starting at start and returns the offset
+// just past its last byte, excluding the terminating delimiter or host
+// quote. Statements outside of a host string must end with a delimiter, or
+// terminated is false; those running to the end of text are accepted.
+func statementEnd(text string, start int, host byte, delimiter string) (end int, terminated bool) {
+	wordDelimiter := isWordDelimiter(delimiter)
+	var quote byte
+	depth := 0
+	for i := start; i < len(text); i++ {
+		c := text[i]
+
+		// Backslashes escape the next byte in host strings and in the SQL
+		// literals of MySQL
+		if c == '\\' && (host != 0 || quote != 0) {
+			i++
+			continue
+		}
+
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			} else if c == host {
+				return i, true
+			}
+			continue
+		}
+
+		switch {
+		case c == host:
+			return i, true
+		case depth == 0 && !wordDelimiter && delimiter != ";" && strings.HasPrefix(text[i:], delimiter):
+			return i, true
+		case c == '\n' && wordDelimiter && isDelimiterLine(text[i+1:], delimiter):
+			return i, true
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth = max(depth-1, 0)
+		case c == ';' && depth == 0 && (delimiter == ";" || wordDelimiter):
+			return i, true
+		case c == '\n' && host == 0 && delimiter == ";" && strings.HasPrefix(strings.TrimLeft(text[i+1:], " \t\r"), "\n"):
+			return i, false
+		case strings.HasPrefix(text[i:], "--"):
+			// Line comments end at the newline, or with the host string
+			for i+1 < len(text) && text[i+1] != '\n' && text[i+1] != host {
+				i++
+			}
+		case strings.HasPrefix(text[i:], "/*"):
+			if n := strings.Index(text[i+2:], "*/"); n >= 0 {
+				i += n + 3
+			} else {
+				i = len(text) - 1
+			}
+		case c == '$':
+			if tag, ok := dollarTag(text[i:]); ok {
+				if n := strings.Index(text[i+len(tag):], tag); n >= 0 {
+					i += len(tag) + n + len(tag) - 1
+				} else {
+					i = len(text) - 1
+				}
+			}
+		}
+	}
+	return len(text), true
+}
+
+// isWordDelimiter reports whether delimiter is made of letters, like GO.
+func isWordDelimiter(delimiter string) bool {
+	for i := 0; i < len(delimiter); i++ {
+		if !(delimiter[i] >= 'a' && delimiter[i] <= 'z' || delimiter[i] >= 'A' && delimiter[i] <= 'Z') {
+			return false
+		}
+	}
+	return delimiter != ""
+}
+
+// isDelimiterLine reports whether the line text starts with holds only the
+// word delimiter, in any case.
+func isDelimiterLine(text, delimiter string) bool {
+	line, _, _ := strings.Cut(text, "\n")
+	return strings.EqualFold(strings.TrimSpace(line), delimiter)
+}
+
+// hostQuote returns the quote of the host language string the word at pos
+// is in, judging by the quotes earlier on its line, or 0 if it isn't in a
+// string. ok is false when the word is in a string but isn't its first word,
+// as SQL keywords in the middle of a string are usually prose.
+func hostQuote(text string, pos int) (quote byte, ok bool) {
+	lineStart := strings.LastIndexByte(text[:pos], '\n') + 1
+	opened := 0
+	for i := lineStart; i < pos; i++ {
+		c := text[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\'' || c == '`'):
+			quote = c
+			opened = i
+		}
+	}
+	if quote != 0 && strings.TrimSpace(text[opened+1:pos]) != "" {
+		return quote, false
+	}
+	return quote, true
+}
+
+// inLineComment reports whether pos follows a //, # or -- line comment
+// marker, or is on a line continuing a /* */ block comment.
+func inLineComment(text string, pos int) bool {
+	line := text[strings.LastIndexByte(text[:pos], '\n')+1 : pos]
+	trimmed := strings.TrimSpace(line)
+	return strings.Contains(line, "//") ||
+		strings.Contains(line, "--") ||
+		strings.HasPrefix(trimmed, "#") ||
+		strings.HasPrefix(trimmed, "*") ||
+		strings.HasPrefix(trimmed, "/*")
+}
+
+// isWordStart reports whether a word starts at pos, as opposed to pos being
+// inside an identifier, a variable like $select or a member like ->select.
+func isWordStart(text string, pos int) bool {
+	c := text[pos]
+	if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+		return false
+	}
+	if pos == 0 {
+		return true
+	}
+	prev := text[pos-1]
+	return !isIdentByte(prev) && prev != '$' && prev != '.' && prev != '>' && prev != ':'
+}
+
+// skipWord returns the position of the last byte of the word at pos.
+func skipWord(text string, pos int) int {
+	for pos+1 < len(text) && isIdentByte(text[pos+1]) {
+		pos++
+	}
+	return pos
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"duplicate-query/pkg/duplicate"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	sarifRuleID  = "duplicate-query"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// printSARIF writes a SARIF 2.1.0 log with one result per occurrence, for
+// GitHub code scanning and similar tools.
+func printSARIF(w io.Writer, duplicates map[string][]duplicate.QueryResult, opts Options) error {
+	results := []sarifResult{}
+	for _, k := range sortedKeys(duplicates, opts.SortBy) {
+		message := fmt.Sprintf("Query is duplicated %d times: %s", len(duplicates[k]), k)
+		for _, result := range duplicates[k] {
+			results = append(results, sarifResult{
+				RuleID:  sarifRuleID,
+				Level:   "warning",
+				Message: sarifMessage{Text: message},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: sarifURI(result.FilePath)},
+						Region:           sarifRegion{StartLine: result.Line},
+					},
+				}},
+			})
+		}
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{
+				Driver: sarifDriver{
+					Name:    "duplicate-query",
+					Version: toolVersion(),
+					Rules: []sarifRule{{
+						ID:               sarifRuleID,
+						ShortDescription: sarifMessage{Text: "SQL query is duplicated across the codebase"},
+					}},
+				},
+			},
+			Results: results,
+		}},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
+
+// sarifURI turns a file path into a URI reference. Relative paths stay
+// relative so they resolve against the repository root.
+func sarifURI(path string) string {
+	if filepath.IsAbs(path) {
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	}
+	return (&url.URL{Path: strings.TrimPrefix(filepath.ToSlash(path), "./")}).String()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"duplicate-query/pkg/duplicate"
+)
+
+// stats holds the numbers printed by -stats.
+type stats struct {
+	Files       int
+	Queries     int
+	Unique      int
+	Discovery   time.Duration
+	Analysis    time.Duration
+	Groups      int
+	Occurrences int
+}
+
+// printStats writes the scan statistics, meant for stderr so they don't mix
+// with the results.
+func printStats(w io.Writer, s stats, duplicates map[string][]duplicate.QueryResult) {
+	s.Groups = len(duplicates)
+	for _, results := range duplicates {
+		s.Occurrences += len(results)
+	}
+
+	// The share of queries that repeat an earlier one, at a glance
+	ratio := 0.0
+	if s.Queries > 0 {
+		ratio = float64(s.Queries-s.Unique) / float64(s.Queries) * 100
+	}
+	fmt.Fprintf(w, "%d queries, %d unique, %.1f%% duplicated\n", s.Queries, s.Unique, ratio)
+
+	fmt.Fprintf(w, "Files scanned:          %d\n", s.Files)
+	fmt.Fprintf(w, "Queries found:          %d\n", s.Queries)
+	fmt.Fprintf(w, "Unique queries:         %d\n", s.Unique)
+	fmt.Fprintf(w, "Duplicate queries:      %d\n", s.Groups)
+	fmt.Fprintf(w, "Duplicate occurrences:  %d\n", s.Occurrences)
+	fmt.Fprintf(w, "File discovery:         %v\n", s.Discovery.Round(time.Millisecond))
+	fmt.Fprintf(w, "Analysis:               %v\n", s.Analysis.Round(time.Millisecond))
+}
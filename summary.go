@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"duplicate-query/pkg/duplicate"
+)
+
+// summary holds the headline numbers printed by -summary-only.
+type summary struct {
+	DuplicateQueries int `json:"duplicate_queries"`
+	Occurrences      int `json:"occurrences"`
+}
+
+// printSummary writes only the number of duplicate queries and of their
+// occurrences, for quick checks such as CI gates.
+func printSummary(w io.Writer, duplicates map[string][]duplicate.QueryResult, opts Options) error {
+	s := summary{DuplicateQueries: len(duplicates)}
+	for _, results := range duplicates {
+		s.Occurrences += len(results)
+	}
+
+	switch opts.Format {
+	case "text":
+		if s.DuplicateQueries == 0 {
+			if !opts.Quiet {
+				fmt.Fprintln(w, "No duplicate queries found")
+			}
+			return nil
+		}
+		fmt.Fprintf(w, "Found %d duplicate queries with %d occurrences%s\n", s.DuplicateQueries, s.Occurrences, sampleNote(opts))
+		return nil
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(s)
+	default:
+		return fmt.Errorf("output format %q is not supported with -summary-only", opts.Format)
+	}
+}
+
+// status is the compact JSON written to -status-file for CI systems.
+type status struct {
+	DuplicateGroups  int `json:"duplicate_groups"`
+	TotalOccurrences int `json:"total_occurrences"`
+	FilesScanned     int `json:"files_scanned"`
+	ExitCode         int `json:"exit_code"`
+}
+
+// writeStatus writes the status of a finished scan to path, whatever the
+// output format.
+func writeStatus(path string, duplicates map[string][]duplicate.QueryResult, filesScanned, exitCode int) error {
+	s := status{DuplicateGroups: len(duplicates), FilesScanned: filesScanned, ExitCode: exitCode}
+	for _, results := range duplicates {
+		s.TotalOccurrences += len(results)
+	}
+
+	f, err := openOutput(path)
+	if err != nil {
+		return fmt.Errorf("error writing status file: %v", err)
+	}
+	err = json.NewEncoder(f).Encode(s)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing status file: %v", err)
+	}
+	return nil
+}
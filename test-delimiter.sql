@@ -0,0 +1,20 @@
+-- MySQL script with stored programs
+DELIMITER $$
+
+CREATE PROCEDURE archive_orders(IN cutoff DATE)
+BEGIN
+    INSERT INTO orders_archive SELECT * FROM orders WHERE created_at < cutoff;
+
+    DELETE FROM orders WHERE created_at < cutoff;
+END$$
+
+CREATE PROCEDURE archive_invoices(IN cutoff DATE)
+BEGIN
+    INSERT INTO invoices_archive SELECT * FROM invoices WHERE created_at < cutoff;
+    DELETE FROM invoices WHERE created_at < cutoff;
+END$$
+
+DELIMITER ;
+
+SELECT * FROM orders WHERE created_at < '2024-01-01';
+SELECT * FROM orders WHERE created_at < '2023-01-01';  -- Duplicate of the query above
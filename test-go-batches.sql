@@ -0,0 +1,11 @@
+-- SQL Server script, run with -delimiter=GO
+UPDATE customers SET tier = 'gold' WHERE total_spent > 1000
+GO
+UPDATE customers SET tier = 'silver' WHERE total_spent > 500
+GO
+SELECT name
+FROM customers
+WHERE tier = 'gold'
+
+ORDER BY name
+go
@@ -0,0 +1,8 @@
+<?php
+// Mixed line endings: CRLF, then lone CR, then LF
+$a = "SELECT * FROM line_endings WHERE id = 1";
+
+$b = "SELECT *
+     FROM line_endings WHERE id = 2";$c = "SELECT * FROM line_endings WHERE id = 3";$d = "SELECT * FROM line_endings WHERE id = 4";
+
+$e = "SELECT * FROM line_endings WHERE id = 5"; // Found at lines 3, 5, 7, 10 and 12
@@ -0,0 +1,25 @@
+package com.example.shop;
+
+import java.time.Instant;
+import java.util.List;
+
+public interface OrderRepository extends JpaRepository<Order, Long> {
+    String BY_CUSTOMER = "SELECT * FROM orders WHERE customer_id = ? AND status = 'open'";
+
+    @Query(value = "SELECT * FROM orders WHERE customer_id = :customerId AND status = 'paid'", nativeQuery = true)
+    List<Order> findPaid(@Param("customerId") long customerId);  // Duplicate of BY_CUSTOMER with -merge-placeholders
+
+    @Query(value = "SELECT * FROM orders " +
+            "WHERE id = :id", nativeQuery = true)
+    Order findNative(@Param("id") long id);
+
+    @Query(value = """
+            SELECT *
+            FROM orders
+            WHERE id = :id
+            """, nativeQuery = true)
+    Order findNativeBlock(@Param("id") long id);  // Duplicate of findNative
+
+    @Query("SELECT o FROM Order o WHERE o.total > ?1")
+    List<Order> findLarge(long total);
+}
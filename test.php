@@ -36,4 +36,152 @@ $q10 = "SELECT department, SUM(salary) as total_payroll
 // Updates and inserts
 $q11 = "UPDATE users SET last_login = NOW() WHERE id = 42";
 $q12 = "INSERT INTO logs (user_id, action, timestamp) VALUES (123, 'login', NOW())";
-$q13 = "UPDATE users SET last_login = NOW() WHERE id = 42";  // Duplicate of q11
\ No newline at end of file
+$q13 = "UPDATE users SET last_login = NOW() WHERE id = 42";  // Duplicate of q11
+
+// Comments
+$q14 = "SELECT name FROM tags /* lookup by id */ WHERE id = 7";
+$q15 = "SELECT name FROM tags WHERE id = 7";  // Duplicate of q14 once comments are stripped
+
+// Escaped quotes inside string literals
+$q16 = "SELECT id FROM notes WHERE body = 'it''s, (really) fine'";
+$q17 = "SELECT id FROM notes WHERE body = 'don\'t (stop), ok'";  // Duplicate of q16
+
+// IN lists of different lengths
+$q18 = "SELECT * FROM orders WHERE id IN (1, 2, 3)";
+$q19 = "SELECT * FROM orders WHERE id IN (4,5)";  // Duplicate of q18
+
+// Queries differing only by alias names (grouped with -normalize-aliases)
+$q20 = "SELECT u.id, u.email FROM users u WHERE u.active = 1";
+$q21 = "SELECT a.id, a.email FROM users AS a WHERE a.active = 1";
+
+// MySQL and standard pagination syntax
+$q22 = "SELECT id FROM posts ORDER BY id LIMIT 20, 10";
+$q23 = "SELECT id FROM posts ORDER BY id LIMIT 10 OFFSET 20";  // Duplicate of q22
+
+// Queries differing only by column order (grouped with -sort-columns)
+$q24 = "INSERT INTO audit (user_id, action) VALUES (1, 'view')";
+$q25 = "INSERT INTO audit (action, user_id) VALUES ('edit', 2)";
+$q26 = "SELECT DISTINCT email, id FROM members WHERE id = 3";
+$q27 = "SELECT DISTINCT id, email FROM members WHERE id = 4";
+
+// PostgreSQL dollar-quoted literals
+$q28 = 'SELECT $$a; b$$ AS note FROM docs WHERE id = 1';
+$q29 = 'SELECT $tag$c $$ d$tag$ AS note FROM docs WHERE id = 2';  // Duplicate of q28
+
+// Common table expressions and stored procedure calls
+$q30 = "WITH recent AS (
+            SELECT user_id, MAX(created_at) AS last_seen FROM events GROUP BY user_id
+        ), active AS (SELECT user_id FROM recent WHERE last_seen > NOW() - INTERVAL 7 DAY)
+        SELECT u.email FROM users u JOIN active a ON a.user_id = u.id";
+$q31 = "with recent as (select user_id, max(created_at) as last_seen from events group by user_id),
+        active as (select user_id from recent where last_seen > now() - interval 30 day)
+        select u.email from users u join active a on a.user_id = u.id";  // Duplicate of q30
+$q32 = "WITH RECURSIVE tree (id, parent_id) AS (SELECT id, parent_id FROM categories WHERE id = 1 UNION ALL SELECT c.id, c.parent_id FROM categories c JOIN tree t ON c.parent_id = t.id) SELECT id FROM tree";
+$q33 = "CALL refresh_totals(12, 'daily')";
+$q34 = "CALL refresh_totals(7, 'weekly')";  // Duplicate of q33
+
+// Prose and method calls that look like SQL are not extracted by the tokenizer
+// select the users from the cache; fall back to the database
+$help = "Please select an option from the menu";
+$rows = $db->select('users');
+
+// Queries built with string concatenation and interpolation
+$q35 = "SELECT id, total FROM invoices " .
+       "WHERE customer_id = " . $customerId . " AND status = 'open'";
+$q36 = "SELECT id, total FROM invoices WHERE customer_id = {$customer->id} AND status = 'open'";  // Duplicate of q35
+$q37 = 'SELECT sku FROM stock WHERE warehouse = ' . $warehouse->id;
+$q38 = 'SELECT sku FROM stock WHERE warehouse = ' . $_GET['warehouse'];  // Duplicate of q37
+
+// Inequality operators and boolean literals (grouped with -normalize-booleans)
+$q39 = "SELECT id FROM coupons WHERE code <> 'FREE' AND expired = TRUE";
+$q40 = "SELECT id FROM coupons WHERE code != 'SALE' AND expired = 1";
+
+// Heredoc and nowdoc queries, with semicolons in their bodies
+$q41 = <<<SQL
+    SELECT id, name FROM suppliers
+    WHERE country = '$country' AND note <> 'a; b'
+    ORDER BY name
+    SQL;
+$q42 = <<<"SQL"
+SELECT id, name FROM suppliers
+WHERE country = '{$filters['country']}' AND note != 'c; d'
+ORDER BY name
+SQL;  // Duplicate of q41
+$q43 = <<<'SQL'
+    DELETE FROM carts WHERE updated_at < NOW() - INTERVAL 30 DAY; -- cleanup
+    SQL;
+$q44 = <<<'SQL'
+    DELETE FROM carts WHERE updated_at < NOW() - INTERVAL 7 DAY; -- cleanup
+    SQL;  // Duplicate of q43
+
+// Quoted identifiers
+$q45 = 'SELECT `id` FROM `order` WHERE `status` = "paid"';
+$q46 = 'SELECT id FROM `order` WHERE status = "shipped"';  // Duplicate of q45
+$q47 = 'SELECT "id" FROM "order" WHERE "status" = \'paid\'';
+$q48 = "SELECT id FROM \"order\" WHERE status = 'shipped'";  // Duplicate of q47 with -dialect=postgres
+
+// Spacing between function names and their parentheses
+$q49 = "SELECT COUNT(*), SUM(total) FROM orders WHERE shop_id = 1";
+$q50 = "select count( * ), sum (total) from orders where shop_id = 2";  // Duplicate of q49
+$q51 = "SELECT order_total(id), COUNT (*) FROM orders WHERE id IN (1, 2)";
+$q52 = "SELECT order_total ( id ), count(*) FROM orders WHERE id IN(3)";  // Duplicate of q51
+
+// Queries inside loops, possible N+1 queries
+foreach ($orderIds as $orderId) {
+    $items = $db->query("SELECT * FROM order_items WHERE order_id = $orderId");
+}
+for ($i = 0; $i < count($users); $i++) {
+    if ($users[$i]['active']) {
+        $db->query("UPDATE users SET last_seen = NOW() WHERE id = {$users[$i]['id']}");
+    }
+}
+function loadItems($db, $orderId) {
+    return $db->query("SELECT * FROM order_items WHERE order_id = 7");  // Duplicate of the first, not in a loop
+}
+
+// Multi-row inserts
+$q53 = "INSERT INTO tags (post_id, name) VALUES (1, 'php')";
+$q54 = "INSERT INTO tags (post_id, name) VALUES (1, 'go'), (2, 'sql'), (3, 'php')";  // Duplicate of q53
+$q55 = "INSERT INTO tags (post_id, name) VALUES (1, 'go'), (2, NULL)";  // Not a duplicate, the rows differ
+
+// Digits in names are not numbers
+$q56 = "SELECT col1 FROM t2 WHERE id = 1";
+$q57 = "SELECT col2 FROM t2 WHERE id = 2";  // Not a duplicate of q56, col1 and col2 differ
+$q58 = "SELECT col1 FROM t2 WHERE id = 3";  // Duplicate of q56
+$q59 = "SELECT price FROM products WHERE price > 9.99";
+$q60 = "SELECT price FROM products WHERE price > 10";  // Duplicate of q59
+
+// Prepared statement placeholders, duplicates of q61 with -merge-placeholders
+$q61 = "SELECT * FROM invoices WHERE id = 10 AND status = 'open'";
+$q62 = "SELECT * FROM invoices WHERE id = ? AND status = ?";
+$q63 = "SELECT * FROM invoices WHERE id = :id AND status = :status";
+$q64 = 'SELECT * FROM invoices WHERE id = $1 AND status = $2';
+$q65 = "SELECT * FROM invoices WHERE id = @p1 AND status = @p2";
+$q66 = "SELECT * FROM invoices WHERE id IN (?, ?, ?) AND created_at::date = :day";
+$q67 = "SELECT * FROM invoices WHERE id IN (1, 2) AND created_at::date = '2024-05-01'";  // Duplicate of q66 with -merge-placeholders
+
+// Occurrences excluded with a dqf:ignore comment, reported as ignored
+$q68 = "SELECT * FROM audit_log WHERE user_id = 1";
+$q69 = "SELECT * FROM audit_log WHERE user_id = 2";  // dqf:ignore, intentional copy of q68
+// dqf:ignore
+$q70 = "SELECT * FROM audit_log WHERE user_id = 3";
+
+// Semicolons inside string literals don't end the statement
+$q71 = "INSERT INTO notes (body) VALUES ('first; second')";
+$q72 = "INSERT INTO notes (body) VALUES ('third')";  // Duplicate of q71
+
+// CASE expressions that only differ by constants and spacing
+$q73 = "SELECT id, CASE WHEN status = 1 THEN 'new' WHEN status = 2 THEN 'paid' ELSE 'other' END AS label FROM orders";
+$q74 = "SELECT id, CASE WHEN status=3 THEN'sent'WHEN status=4 THEN'done'ELSE'other'END AS label FROM orders";  // Duplicate of q73
+$q75 = "SELECT id, CASE WHEN total > 100 THEN 'big' ELSE NULL END AS size FROM orders";
+$q76 = "SELECT id, CASE WHEN total > 500 THEN 'huge' END AS size FROM orders";  // Duplicate of q75, ELSE NULL is the default
+
+// ORDER BY lists only differing in an explicit ASC or in key order
+$q77 = "SELECT * FROM products ORDER BY category ASC, price DESC";
+$q78 = "SELECT * FROM products ORDER BY category, price DESC";  // Duplicate of q77 with -normalize-orderby
+$q79 = "SELECT * FROM products ORDER BY price DESC, category";  // Duplicate of q77 with -sort-orderby
+
+// The same query on per-period tables, duplicates with -normalize-table-suffixes
+$q80 = "SELECT SUM(total) FROM orders_2023 WHERE customer_id = 7";
+$q81 = "SELECT SUM(total) FROM orders_2024 WHERE customer_id = 9";  // Duplicate of q80 with -normalize-table-suffixes
+$q82 = "SELECT SUM(total) FROM orders_2024_06 WHERE customer_id = 3";  // Duplicate of q80 with -normalize-table-suffixes
@@ -0,0 +1,45 @@
+import sqlite3
+
+conn = sqlite3.connect("shop.db")
+cursor = conn.cursor()
+
+# Triple-quoted queries
+cursor.execute("""
+    SELECT id, name, email
+    FROM customers
+    WHERE status = %s
+""", ("active",))
+
+cursor.execute("""SELECT id, name, email FROM customers WHERE status = %(status)s""", {"status": "active"})  # Duplicate of the first query
+
+# Parenthesized implicit concatenation
+query = (
+    "SELECT o.id, o.total "
+    "FROM orders o "
+    "WHERE o.customer_id = :customer_id"
+)
+other = ("SELECT o.id, o.total FROM orders o "
+         "WHERE o.customer_id = {customer_id}")  # Duplicate of query
+
+# execute and executemany arguments
+cursor.executemany("INSERT INTO order_items (order_id, product_id) VALUES (?, ?)", rows)
+cursor.executemany('INSERT INTO order_items (order_id, product_id) VALUES (%s, %s)', rows)  # Duplicate
+cursor.execute(f"UPDATE orders SET status = 'shipped' WHERE id = {order.id}")
+cursor.execute(r'UPDATE orders SET status = "shipped" WHERE id = %s', (order_id,))  # Duplicate
+
+# Not SQL
+message = "Please select a product from the list"
+cursor.execute("SELECT created_at::date FROM orders WHERE note = 'at 10:30'")
+
+# Queries inside loops, possible N+1 queries
+for order_id in order_ids:
+    if order_id:
+        cursor.execute("SELECT * FROM order_items WHERE order_id = %s", (order_id,))
+
+
+def load_items(order_id):
+    cursor.execute("SELECT * FROM order_items WHERE order_id = %s", (order_id,))  # Not in a loop
+
+
+# Excluded with a dqf:ignore comment
+cursor.execute("SELECT * FROM order_items WHERE order_id = %s", (1,))  # dqf:ignore
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"duplicate-query/pkg/duplicate"
+)
+
+// treeDir is a directory of the -tree output, holding the occurrences of
+// duplicate queries found below it.
+type treeDir struct {
+	dirs  map[string]*treeDir
+	files map[string]map[string][]int // Lines per normalized query per file
+	count int
+}
+
+func newTreeDir() *treeDir {
+	return &treeDir{dirs: make(map[string]*treeDir), files: make(map[string]map[string][]int)}
+}
+
+// add records an occurrence of the normalized query k at path, a slash
+// separated path relative to the directory.
+func (d *treeDir) add(path, k string, line int) {
+	d.count++
+	dir, rest, found := strings.Cut(path, "/")
+	if !found {
+		if d.files[path] == nil {
+			d.files[path] = make(map[string][]int)
+		}
+		d.files[path][k] = append(d.files[path][k], line)
+		return
+	}
+	if d.dirs[dir] == nil {
+		d.dirs[dir] = newTreeDir()
+	}
+	d.dirs[dir].add(rest, k, line)
+}
+
+// printTree writes the duplicate queries nested under the directories and
+// files they occur in. Directories with a single subdirectory and no files
+// are printed on one line, like src/app/.
+func printTree(w io.Writer, duplicates map[string][]duplicate.QueryResult, opts Options) error {
+	if opts.Format != "text" {
+		return fmt.Errorf("output format %q is not supported with -tree", opts.Format)
+	}
+	duplicates = topDuplicates(duplicates, opts)
+
+	root := newTreeDir()
+	base := reportRoot(opts)
+	for k, results := range duplicates {
+		for _, result := range results {
+			path := filepath.ToSlash(filepath.Clean(relativePath(base, result.FilePath)))
+			root.add(strings.TrimPrefix(path, "/"), k, result.Line)
+		}
+	}
+
+	if len(duplicates) == 0 {
+		if !opts.Quiet {
+			fmt.Fprintln(w, "No duplicate queries found")
+		}
+		return nil
+	}
+	if !opts.Quiet {
+		fmt.Fprintf(w, "Found %d duplicate queries\n", len(duplicates))
+	}
+	printTreeDir(w, root, duplicates, 0)
+	return nil
+}
+
+// printTreeDir writes the contents of d indented by depth levels: its
+// subdirectories, then its files with the queries found in each, ordered by
+// first line.
+func printTreeDir(w io.Writer, d *treeDir, duplicates map[string][]duplicate.QueryResult, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	for _, name := range sortedNames(d.dirs) {
+		dir := d.dirs[name]
+		for len(dir.files) == 0 && len(dir.dirs) == 1 {
+			for child, next := range dir.dirs {
+				name, dir = name+"/"+child, next
+			}
+		}
+		fmt.Fprintf(w, "%s%s/ (%d)\n", indent, name, dir.count)
+		printTreeDir(w, dir, duplicates, depth+1)
+	}
+
+	for _, name := range sortedNames(d.files) {
+		queries := d.files[name]
+		keys := make([]string, 0, len(queries))
+		count := 0
+		for k, lines := range queries {
+			sort.Ints(lines)
+			keys = append(keys, k)
+			count += len(lines)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if queries[keys[i]][0] != queries[keys[j]][0] {
+				return queries[keys[i]][0] < queries[keys[j]][0]
+			}
+			return keys[i] < keys[j]
+		})
+
+		fmt.Fprintf(w, "%s%s (%d)\n", indent, name, count)
+		for _, k := range keys {
+			lines := make([]string, len(queries[k]))
+			for i, line := range queries[k] {
+				lines[i] = strconv.Itoa(line)
+			}
+			fmt.Fprintf(w, "%s  Lines: %s -- Count: %d -- Normalized Query:\t %s\n", indent, strings.Join(lines, ", "), len(duplicates[k]), k)
+		}
+	}
+}
+
+// sortedNames returns the keys of m in alphabetical order.
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}